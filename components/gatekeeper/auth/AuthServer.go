@@ -15,27 +15,24 @@
 package auth
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"github.com/kubeflow/kubeflow/components/gatekeeper/cmd/gatekeeper/options"
+	"github.com/kubeflow/kubeflow/components/gatekeeper/credentials"
+	"github.com/kubeflow/kubeflow/components/gatekeeper/session"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
-	"math/rand"
 	"net/http"
 	"path"
 	"strings"
-	"sync"
 	"time"
 )
 
-// start with easy case: each server struct only has one valid pair of u/p
 type authServer struct {
-	username	string
-	// password bcrypt hash
-	pwhash      string
-	// authorized cookies and their expire time (12 hour by default)
-	cookies 	map[string]time.Time
-	serverMux   sync.Mutex
+	// users allowed to log in
+	users       credentials.Store
+	// authorized cookies and their user and expire time (12 hour by default)
+	sessions    session.Store
 	allowHttp	bool
 }
 
@@ -44,15 +41,10 @@ const LoginPagePath = "kflogin"
 const LoginPageHeader = "x-from-login"
 const WhoAmIPath = "whoami"
 
-func NewAuthServer(opt *options.ServerOption) *authServer {
-	data, err := base64.StdEncoding.DecodeString(opt.Pwhash)
-	if err != nil {
-		log.Fatal("error:", err)
-	}
+func NewAuthServer(opt *options.ServerOption, users credentials.Store, sessions session.Store) *authServer {
 	server := &authServer{
-		username: opt.Username,
-		pwhash: string(data),
-		cookies: make(map[string]time.Time),
+		users: users,
+		sessions: sessions,
 		allowHttp: opt.AllowHttp,
 	}
 	return server
@@ -90,11 +82,11 @@ func (s *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.authpwd(r) == true {
+	if username, ok := s.authpwd(r); ok {
 		log.Infof("P/W passed")
 		// Handle request from login page
 		if r.Header.Get(LoginPageHeader) != "" {
-			s.setCookieAndReset(w, r)
+			s.setCookieAndReset(w, r, username)
 			return
 		}
 		// Allow requst from API call
@@ -114,43 +106,47 @@ func (s *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.redirectToLogin(w, r)
 }
 
-// auth with basic pw
-func (s *authServer) authpwd(r *http.Request) bool {
+// auth with basic pw, returns the authenticated username
+func (s *authServer) authpwd(r *http.Request) (string, bool) {
 	auth := r.Header.Get("Authorization")
 	if !strings.HasPrefix(strings.ToLower(auth), "basic ") {
-		return false
+		return "", false
 	}
 
 	upBytes, err := base64.StdEncoding.DecodeString(auth[len("basic "):])
 	if err != nil {
-		return false
+		return "", false
 	}
 
-	namepw := strings.Split(string(upBytes), ":")
+	namepw := strings.SplitN(string(upBytes), ":", 2)
 
 	if len(namepw) != 2 {
-		return false
+		return "", false
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(s.pwhash), []byte(namepw[1]))
-	if namepw[0] == s.username && err == nil {
-
-		return true
+	if !credentials.Authenticate(s.users, namepw[0], namepw[1]) {
+		return "", false
 	}
-	return false
+	return namepw[0], true
 }
 
 // auth with cookie
 func (s *authServer) authCookie(r *http.Request) bool {
 	if cookie, err := r.Cookie(CookieName); err == nil {
-		if val, ok := s.cookies[cookie.Value]; ok {
-			if time.Now().Before(val) {
-				log.Info("cookie auth: passed! %v", cookie.Value)
-				return true
-			}
-			log.Info("cookie auth: cookie value expired!")
+		val, ok, err := s.sessions.Get(cookie.Value)
+		if err != nil {
+			log.Errorf("cookie auth: failed to get session: %v", err)
 			return false
 		}
-		log.Info("cookie auth: cookie value not found! %v", cookie.Value)
+		if ok {
+			// Users removed or disabled since login lose access immediately.
+			if u, found := s.users.Lookup(val.Username); !found || !u.Enabled {
+				log.Infof("cookie auth: user %v no longer enabled!", val.Username)
+				return false
+			}
+			log.Info("cookie auth: passed!")
+			return true
+		}
+		log.Info("cookie auth: cookie value not found or expired!")
 		return false
 	}
 	log.Info("cookie auth: cookie does't exist in request!")
@@ -162,31 +158,36 @@ func (s *authServer) redirectToLogin(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "https://" + path.Join(r.Host, LoginPagePath), http.StatusTemporaryRedirect)
 }
 
-func generateCookieValue() string {
-	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 20)
-	for i := range b {
-		b[i] = chars[rand.Intn(len(chars))]
+// Cookie values are random, since sessions are shared and outlive the
+// gatekeeper process.
+func generateCookieValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return string(b)
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func (s *authServer) addNewCookieValue(cookieVal string) {
-	s.serverMux.Lock()
-	defer s.serverMux.Unlock()
+// Set auth cookie and reset, UI will redirect to kubeflow central dashboard
+func (s *authServer) setCookieAndReset(w http.ResponseWriter, r *http.Request, username string) {
+	cookieVal, err := generateCookieValue()
+	if err != nil {
+		log.Errorf("cookie set: failed to generate cookie value: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 	// Cookie expire after 12 hours
+	expires := time.Now().Add(12 * time.Hour)
+	if err := s.sessions.Put(cookieVal, session.Session{Username: username, Expires: expires}); err != nil {
+		log.Errorf("cookie set: failed to store session: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 	log.Info("cookie set: set new cookie value!")
-	s.cookies[cookieVal] = time.Now().Add(12 * time.Hour)
-}
-
-// Set auth cookie and reset, UI will redirect to kubeflow central dashboard
-func (s *authServer) setCookieAndReset(w http.ResponseWriter, r *http.Request) {
-	cookieVal := generateCookieValue()
-	s.addNewCookieValue(cookieVal)
 	cookie := http.Cookie{
 		Name: CookieName,
 		Value: cookieVal,
-		Expires: time.Now().Add(12 * time.Hour),
+		Expires: expires,
 		Path: "/",
 		// prevent cross-origin information leakage.
 		SameSite: http.SameSiteStrictMode,
@@ -201,7 +202,6 @@ func (s *authServer) Start(port int) {
 	if port <= 0 {
 		log.Fatal("port must be > 0.")
 	}
-	rand.Seed(time.Now().UTC().UnixNano())
 	log.Info("Auth Service starts")
 	// All request
 	http.Handle("/", s)
@@ -15,11 +15,19 @@
 package main
 
 import (
+	"encoding/base64"
 	"flag"
+	"fmt"
+	"strings"
+
 	"github.com/kubeflow/kubeflow/components/gatekeeper/auth"
 	"github.com/kubeflow/kubeflow/components/gatekeeper/cmd/gatekeeper/options"
+	"github.com/kubeflow/kubeflow/components/gatekeeper/credentials"
+	"github.com/kubeflow/kubeflow/components/gatekeeper/session"
 	"github.com/onrik/logrus/filename"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 func init() {
@@ -34,9 +42,124 @@ func main() {
 	sop.AddFlags(flag.CommandLine)
 
 	flag.Parse()
-	if sop.Username == "" || sop.Pwhash == "" {
-		log.Fatal("Username or Pwhash empty, exit now")
+	stop := make(chan struct{})
+	users, err := newCredentialStore(sop, stop)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sessions, err := newSessionStore(sop, stop)
+	if err != nil {
+		log.Fatal(err)
 	}
-	s := auth.NewAuthServer(sop)
+	go session.RunExpiry(sessions, sop.SessionExpiryPeriod, stop)
+	s := auth.NewAuthServer(sop, users, sessions)
 	s.Start(8085)
 }
+
+// newKubeClient returns a client of the cluster the gatekeeper runs in.
+func newKubeClient() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// splitNamespacedName splits the <namespace>/<name> value of the flag.
+func splitNamespacedName(flagName, value string) (string, string, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("--%s must be <namespace>/<name>, got %q", flagName, value)
+	}
+	return parts[0], parts[1], nil
+}
+
+// newSessionStore returns the session store selected by the flags and starts
+// watching its object, if any, until stop is closed.
+func newSessionStore(sop *options.ServerOption, stop <-chan struct{}) (session.Store, error) {
+	switch sop.SessionStore {
+	case "memory":
+		return session.NewMemoryStore(), nil
+	case "secret", "configmap":
+		namespace, name, err := splitNamespacedName("session-object", sop.SessionObject)
+		if err != nil {
+			return nil, err
+		}
+		client, err := newKubeClient()
+		if err != nil {
+			return nil, err
+		}
+		kind := session.KindSecret
+		if sop.SessionStore == "configmap" {
+			kind = session.KindConfigMap
+		}
+		store, err := session.NewKubeStore(client, kind, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		go store.Run(stop)
+		if err := store.WaitForSync(stop); err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "redis":
+		if sop.RedisAddress == "" {
+			return nil, fmt.Errorf("--redis-address must be set for the redis session store")
+		}
+		return session.NewRedisStore(sop.RedisAddress, sop.RedisPassword), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q", sop.SessionStore)
+	}
+}
+
+// newCredentialStore returns the store selected by the flags and starts
+// reloading it until stop is closed.
+func newCredentialStore(sop *options.ServerOption, stop <-chan struct{}) (credentials.Store, error) {
+	backends := 0
+	for _, set := range []bool{sop.Username != "" || sop.Pwhash != "", sop.HtpasswdFile != "", sop.CredentialSecret != ""} {
+		if set {
+			backends++
+		}
+	}
+	if backends != 1 {
+		return nil, fmt.Errorf("exactly one of --username/--pwhash, --htpasswd-file or --credential-secret must be set")
+	}
+
+	switch {
+	case sop.HtpasswdFile != "":
+		store, err := credentials.NewFileStore(sop.HtpasswdFile)
+		if err != nil {
+			return nil, err
+		}
+		go store.Run(sop.HtpasswdReload, stop)
+		return store, nil
+	case sop.CredentialSecret != "":
+		namespace, name, err := splitNamespacedName("credential-secret", sop.CredentialSecret)
+		if err != nil {
+			return nil, err
+		}
+		client, err := newKubeClient()
+		if err != nil {
+			return nil, err
+		}
+		store := credentials.NewSecretStore(client, namespace, name, sop.CredentialSecretKey)
+		go store.Run(stop)
+		if err := store.WaitForSync(stop); err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		if sop.Username == "" || sop.Pwhash == "" {
+			return nil, fmt.Errorf("Username or Pwhash empty, exit now")
+		}
+		data, err := base64.StdEncoding.DecodeString(sop.Pwhash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --pwhash: %v", err)
+		}
+		return credentials.NewMemoryStore(credentials.User{
+			Name:         sop.Username,
+			PasswordHash: data,
+			Enabled:      true,
+		}), nil
+	}
+}
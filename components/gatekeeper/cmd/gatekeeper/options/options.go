@@ -14,12 +14,26 @@
 
 package options
 
-import "flag"
+import (
+	"flag"
+	"time"
+)
 
 type ServerOption struct {
 	Username   string
 	Pwhash     string
 	AllowHttp  bool
+	// Users can be loaded from a htpasswd file or Secret instead of flags.
+	HtpasswdFile        string
+	HtpasswdReload      time.Duration
+	CredentialSecret    string
+	CredentialSecretKey string
+	// Sessions are kept in memory, a Secret, a ConfigMap or Redis.
+	SessionStore        string
+	SessionObject       string
+	RedisAddress        string
+	RedisPassword       string
+	SessionExpiryPeriod time.Duration
 	// Email for password reset?
 	// Email                string
 }
@@ -32,5 +46,14 @@ func NewServerOption() *ServerOption {
 func (s *ServerOption) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&s.Username, "username", "", "Username for login")
 	fs.StringVar(&s.Pwhash, "pwhash", "", "Bcrypt hash of password for login.")
+	fs.StringVar(&s.HtpasswdFile, "htpasswd-file", "", "Path of a htpasswd file with the bcrypt hashes of the users allowed to log in.")
+	fs.DurationVar(&s.HtpasswdReload, "htpasswd-reload-period", 10*time.Second, "How often the htpasswd file is checked for changes.")
+	fs.StringVar(&s.CredentialSecret, "credential-secret", "", "<namespace>/<name> of a Secret holding htpasswd data of the users allowed to log in.")
+	fs.StringVar(&s.CredentialSecretKey, "credential-secret-key", "htpasswd", "Key of the credential Secret holding the htpasswd data.")
+	fs.StringVar(&s.SessionStore, "session-store", "memory", "Where login sessions are kept: memory, secret, configmap or redis.")
+	fs.StringVar(&s.SessionObject, "session-object", "", "<namespace>/<name> of the Secret or ConfigMap holding the sessions.")
+	fs.StringVar(&s.RedisAddress, "redis-address", "", "host:port of the Redis server holding the sessions.")
+	fs.StringVar(&s.RedisPassword, "redis-password", "", "Password of the Redis server holding the sessions.")
+	fs.DurationVar(&s.SessionExpiryPeriod, "session-expiry-period", 10*time.Minute, "How often expired sessions are removed from the session store.")
 	fs.BoolVar(&s.AllowHttp, "allowhttp", false, "Whether or not allow http traffic. Http for test only")
 }
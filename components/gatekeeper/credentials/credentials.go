@@ -0,0 +1,113 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentials provides the user stores the gatekeeper authenticates
+// against.
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DisabledPrefix marks the password hash of a disabled user in the
+// htpasswd format, as a locked account in /etc/shadow.
+const DisabledPrefix = "!"
+
+// User is a user allowed to log in.
+type User struct {
+	Name string
+	// PasswordHash is the bcrypt hash of the password.
+	PasswordHash []byte
+	// Enabled is false for users that are known but may not log in.
+	Enabled bool
+}
+
+// Store looks up users by name.
+type Store interface {
+	// Lookup returns the user with the given name, or false if there is none.
+	Lookup(name string) (User, bool)
+}
+
+// Authenticate returns whether the user exists in the store, is enabled and
+// has the given password.
+func Authenticate(s Store, name, password string) bool {
+	u, ok := s.Lookup(name)
+	if !ok || !u.Enabled {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) == nil
+}
+
+// ParseHtpasswd parses users in the htpasswd format: one name:hash pair per
+// line, where the hash is a bcrypt hash as written by htpasswd -B. A hash
+// starting with DisabledPrefix disables the user. Blank lines and lines
+// starting with # are ignored.
+func ParseHtpasswd(data []byte) (map[string]User, error) {
+	users := map[string]User{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected name:hash", n)
+		}
+		u := User{Name: line[:i], Enabled: true}
+		hash := line[i+1:]
+		if strings.HasPrefix(hash, DisabledPrefix) {
+			u.Enabled = false
+			hash = hash[len(DisabledPrefix):]
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("line %d: user %q: %v", n, u.Name, err)
+		}
+		if _, ok := users[u.Name]; ok {
+			return nil, fmt.Errorf("line %d: duplicate user %q", n, u.Name)
+		}
+		u.PasswordHash = []byte(hash)
+		users[u.Name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// userSet is a set of users that is replaced as a whole when its source
+// changes.
+type userSet struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func (s *userSet) Lookup(name string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[name]
+	return u, ok
+}
+
+func (s *userSet) replace(users map[string]User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = users
+}
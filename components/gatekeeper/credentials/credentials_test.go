@@ -0,0 +1,154 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hash(t *testing.T, password string) string {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	alice := hash(t, "alice-pw")
+	bob := hash(t, "bob-pw")
+
+	tests := []struct {
+		name    string
+		data    string
+		enabled map[string]bool
+		wantErr bool
+	}{
+		{
+			name:    "users",
+			data:    "# comment\nalice:" + alice + "\n\nbob:!" + bob + "\n",
+			enabled: map[string]bool{"alice": true, "bob": false},
+		},
+		{
+			name:    "missing hash separator",
+			data:    "alice\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a bcrypt hash",
+			data:    "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate user",
+			data:    "alice:" + alice + "\nalice:" + bob + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		users, err := ParseHtpasswd([]byte(test.data))
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if len(users) != len(test.enabled) {
+			t.Errorf("%s: got %d users, want %d", test.name, len(users), len(test.enabled))
+		}
+		for name, enabled := range test.enabled {
+			if u, ok := users[name]; !ok || u.Enabled != enabled {
+				t.Errorf("%s: got user %q %+v, want enabled %v", test.name, name, u, enabled)
+			}
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := NewMemoryStore(
+		User{Name: "alice", PasswordHash: []byte(hash(t, "alice-pw")), Enabled: true},
+		User{Name: "bob", PasswordHash: []byte(hash(t, "bob-pw")), Enabled: false},
+	)
+
+	tests := []struct {
+		user     string
+		password string
+		want     bool
+	}{
+		{"alice", "alice-pw", true},
+		{"alice", "bob-pw", false},
+		{"bob", "bob-pw", false},
+		{"carol", "alice-pw", false},
+	}
+
+	for _, test := range tests {
+		if got := Authenticate(store, test.user, test.password); got != test.want {
+			t.Errorf("Authenticate(%q, %q) = %v, want %v", test.user, test.password, got, test.want)
+		}
+	}
+
+	store.Delete("alice")
+	if Authenticate(store, "alice", "alice-pw") {
+		t.Errorf("deleted user authenticated")
+	}
+}
+
+func TestFileStoreReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "htpasswd")
+	write := func(data string) {
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice := "alice:" + hash(t, "alice-pw") + "\n"
+	write(alice)
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Authenticate(store, "alice", "alice-pw") {
+		t.Fatalf("alice not authenticated")
+	}
+
+	// An invalid file keeps the previous users.
+	write("alice\n")
+	if err := store.Reload(); err == nil {
+		t.Errorf("expected error reloading invalid file")
+	}
+	if !Authenticate(store, "alice", "alice-pw") {
+		t.Errorf("alice not authenticated after invalid reload")
+	}
+
+	write("alice:!" + hash(t, "alice-pw") + "\nbob:" + hash(t, "bob-pw") + "\n")
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if Authenticate(store, "alice", "alice-pw") {
+		t.Errorf("disabled alice authenticated")
+	}
+	if !Authenticate(store, "bob", "bob-pw") {
+		t.Errorf("bob not authenticated after reload")
+	}
+}
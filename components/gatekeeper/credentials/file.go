@@ -0,0 +1,79 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FileStore holds the users of a htpasswd file.
+type FileStore struct {
+	userSet
+	path string
+	// data is the content the users were last loaded from.
+	data []byte
+}
+
+// NewFileStore returns a store of the users in the htpasswd file at path.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads the users again if the file changed. The previous users are
+// kept if the file cannot be read or parsed.
+func (s *FileStore) Reload() error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	if s.users != nil && bytes.Equal(data, s.data) {
+		return nil
+	}
+	users, err := ParseHtpasswd(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	s.replace(users)
+	s.data = data
+	log.Infof("Loaded %d users from %s", len(users), s.path)
+	return nil
+}
+
+// Run reloads the file every period until stop is closed. The file is
+// polled rather than watched so that updates of mounted Secrets and
+// ConfigMaps, which replace a symlink, are seen as well.
+func (s *FileStore) Run(period time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				log.Errorf("Failed to reload users: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+// MemoryStore holds users in memory. It backs the single user given by
+// flags and is used in tests.
+type MemoryStore struct {
+	userSet
+}
+
+// NewMemoryStore returns a store of the given users.
+func NewMemoryStore(users ...User) *MemoryStore {
+	s := &MemoryStore{userSet{users: map[string]User{}}}
+	for _, u := range users {
+		s.users[u.Name] = u
+	}
+	return s
+}
+
+// Put adds the user, or replaces the user of the same name.
+func (s *MemoryStore) Put(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Name] = u
+}
+
+// Delete removes the user with the given name.
+func (s *MemoryStore) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, name)
+}
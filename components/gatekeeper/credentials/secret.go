@@ -0,0 +1,95 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultSecretKey is the key of the Secret holding the htpasswd data.
+const DefaultSecretKey = "htpasswd"
+
+// SecretStore holds the users of a htpasswd file stored in a key of a
+// Kubernetes Secret. The Secret is watched and the users are replaced
+// whenever it changes; no user may log in while the Secret does not exist.
+type SecretStore struct {
+	userSet
+	namespace string
+	name      string
+	key       string
+	informer  cache.Controller
+}
+
+// NewSecretStore returns a store of the users in the key of the Secret.
+// The store is empty until Run has synced the Secret.
+func NewSecretStore(client kubernetes.Interface, namespace, name, key string) *SecretStore {
+	s := &SecretStore{
+		userSet:   userSet{users: map[string]User{}},
+		namespace: namespace,
+		name:      name,
+		key:       key,
+	}
+	lw := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "secrets", namespace,
+		fields.OneTermEqualSelector("metadata.name", name))
+	_, s.informer = cache.NewInformer(lw, &corev1.Secret{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: s.update,
+		UpdateFunc: func(_, obj interface{}) {
+			s.update(obj)
+		},
+		DeleteFunc: func(interface{}) {
+			log.Infof("Secret %s/%s deleted, removing all users", s.namespace, s.name)
+			s.replace(map[string]User{})
+		},
+	})
+	return s
+}
+
+// Run watches the Secret until stop is closed.
+func (s *SecretStore) Run(stop <-chan struct{}) {
+	s.informer.Run(stop)
+}
+
+// WaitForSync waits until the Secret has been loaded or stop is closed.
+func (s *SecretStore) WaitForSync(stop <-chan struct{}) error {
+	if !cache.WaitForCacheSync(stop, s.informer.HasSynced) {
+		return fmt.Errorf("failed to sync Secret %s/%s", s.namespace, s.name)
+	}
+	return nil
+}
+
+func (s *SecretStore) update(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+	data, ok := secret.Data[s.key]
+	if !ok {
+		log.Errorf("Secret %s/%s has no key %q, keeping previous users", s.namespace, s.name, s.key)
+		return
+	}
+	users, err := ParseHtpasswd(data)
+	if err != nil {
+		log.Errorf("Secret %s/%s: %v, keeping previous users", s.namespace, s.name, err)
+		return
+	}
+	s.replace(users)
+	log.Infof("Loaded %d users from Secret %s/%s", len(users), s.namespace, s.name)
+}
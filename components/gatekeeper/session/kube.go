@@ -0,0 +1,270 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+)
+
+// Kinds of the objects a KubeStore can keep sessions in.
+const (
+	KindSecret    = "Secret"
+	KindConfigMap = "ConfigMap"
+)
+
+// KubeStore keeps sessions in the data of a Secret or ConfigMap, one key per
+// session. The object is created on the first login if it does not exist.
+// Concurrent updates by several replicas are retried on conflict.
+//
+// The object is watched, and cookies are looked up in the watched copy, so
+// that unknown or forged cookies cost no request to the API server. A
+// session put by another replica is known once the watch has seen it. The
+// API server limits objects to 1MiB, so the store holds several thousand
+// sessions; logins fail while it is full, until expired sessions are
+// removed.
+type KubeStore struct {
+	client    kubernetes.Interface
+	kind      string
+	namespace string
+	name      string
+	// cache holds the sessions already decoded or written, so that a login
+	// is valid on this replica before the watch sees it. Sessions are only
+	// removed once they expire, so a cached session stays valid until then.
+	cache    *MemoryStore
+	objects  cache.Store
+	informer cache.Controller
+}
+
+// NewKubeStore returns a store of the sessions in the Secret or ConfigMap.
+// No session is found until Run has synced the object.
+func NewKubeStore(client kubernetes.Interface, kind, namespace, name string) (*KubeStore, error) {
+	k := &KubeStore{
+		client:    client,
+		kind:      kind,
+		namespace: namespace,
+		name:      name,
+		cache:     NewMemoryStore(),
+	}
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{}
+	var obj runtime.Object
+	switch kind {
+	case KindSecret:
+		secrets := client.CoreV1().Secrets(namespace)
+		lw.ListFunc = func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return secrets.List(options)
+		}
+		lw.WatchFunc = func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return secrets.Watch(options)
+		}
+		obj = &corev1.Secret{}
+	case KindConfigMap:
+		configMaps := client.CoreV1().ConfigMaps(namespace)
+		lw.ListFunc = func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return configMaps.List(options)
+		}
+		lw.WatchFunc = func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return configMaps.Watch(options)
+		}
+		obj = &corev1.ConfigMap{}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q, must be %s or %s", kind, KindSecret, KindConfigMap)
+	}
+	k.objects, k.informer = cache.NewInformer(lw, obj, 0, cache.ResourceEventHandlerFuncs{})
+	return k, nil
+}
+
+// Run watches the object until stop is closed.
+func (k *KubeStore) Run(stop <-chan struct{}) {
+	k.informer.Run(stop)
+}
+
+// WaitForSync waits until the object has been loaded or stop is closed.
+func (k *KubeStore) WaitForSync(stop <-chan struct{}) error {
+	if !cache.WaitForCacheSync(stop, k.informer.HasSynced) {
+		return fmt.Errorf("failed to sync %s %s/%s", k.kind, k.namespace, k.name)
+	}
+	return nil
+}
+
+func (k *KubeStore) Get(key string) (Session, bool, error) {
+	if s, ok, _ := k.cache.Get(key); ok {
+		return s, true, nil
+	}
+	value, ok, err := k.lookup(storageKey(key))
+	if err != nil || !ok {
+		return Session{}, false, err
+	}
+	s, err := decode(value)
+	if err != nil {
+		return Session{}, false, err
+	}
+	if s.Expired(k.cache.now()) {
+		return Session{}, false, nil
+	}
+	k.cache.Put(key, s)
+	return s, true, nil
+}
+
+// lookup returns the value of the key in the watched copy of the object.
+func (k *KubeStore) lookup(key string) ([]byte, bool, error) {
+	obj, exists, err := k.objects.GetByKey(k.namespace + "/" + k.name)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		value, ok := o.Data[key]
+		return value, ok, nil
+	case *corev1.ConfigMap:
+		value, ok := o.Data[key]
+		return []byte(value), ok, nil
+	}
+	return nil, false, nil
+}
+
+func (k *KubeStore) Put(key string, s Session) error {
+	value, err := encode(s)
+	if err != nil {
+		return err
+	}
+	err = k.update(func(data map[string][]byte) bool {
+		data[storageKey(key)] = value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return k.cache.Put(key, s)
+}
+
+func (k *KubeStore) Expire(now time.Time) error {
+	k.cache.Expire(now)
+	return k.update(func(data map[string][]byte) bool {
+		changed := false
+		for key, value := range data {
+			// Entries that cannot be decoded are not sessions and are kept.
+			if s, err := decode(value); err == nil && s.Expired(now) {
+				delete(data, key)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// update applies fn to the data of the object and saves it if fn reports a
+// change.
+func (k *KubeStore) update(fn func(data map[string][]byte) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		data, save, err := k.load()
+		if err != nil {
+			return err
+		}
+		if !fn(data) {
+			return nil
+		}
+		return save(data)
+	})
+}
+
+// load returns the data of the object, which is empty if the object does not
+// exist, and a function that saves the data to the object as it was loaded.
+func (k *KubeStore) load() (map[string][]byte, func(map[string][]byte) error, error) {
+	meta := metav1.ObjectMeta{Namespace: k.namespace, Name: k.name}
+	data := map[string][]byte{}
+
+	switch k.kind {
+	case KindSecret:
+		secrets := k.client.CoreV1().Secrets(k.namespace)
+		secret, err := secrets.Get(k.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			secret, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if secret != nil {
+			for key, value := range secret.Data {
+				data[key] = value
+			}
+		}
+		return data, func(data map[string][]byte) error {
+			if secret == nil {
+				_, err := secrets.Create(&corev1.Secret{ObjectMeta: meta, Data: data})
+				return k.conflictIfExists(err)
+			}
+			secret.Data = data
+			_, err := secrets.Update(secret)
+			return err
+		}, nil
+
+	default:
+		configMaps := k.client.CoreV1().ConfigMaps(k.namespace)
+		cm, err := configMaps.Get(k.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if cm != nil {
+			for key, value := range cm.Data {
+				data[key] = []byte(value)
+			}
+		}
+		return data, func(data map[string][]byte) error {
+			strData := map[string]string{}
+			for key, value := range data {
+				strData[key] = string(value)
+			}
+			if cm == nil {
+				_, err := configMaps.Create(&corev1.ConfigMap{ObjectMeta: meta, Data: strData})
+				return k.conflictIfExists(err)
+			}
+			cm.Data = strData
+			_, err := configMaps.Update(cm)
+			return err
+		}, nil
+	}
+}
+
+// conflictIfExists turns the error of creating an object that another
+// replica created in the meantime into a conflict, so that the update is
+// retried.
+func (k *KubeStore) conflictIfExists(err error) error {
+	if apierrors.IsAlreadyExists(err) {
+		resource := schema.GroupResource{Resource: strings.ToLower(k.kind) + "s"}
+		return apierrors.NewConflict(resource, k.name, err)
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore holds sessions in the gatekeeper process. Sessions are lost
+// on restart and are not shared between replicas.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+	now      func() time.Time
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]Session{}, now: time.Now}
+}
+
+func (m *MemoryStore) Get(key string) (Session, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if !ok || s.Expired(m.now()) {
+		return Session{}, false, nil
+	}
+	return s, true, nil
+}
+
+func (m *MemoryStore) Put(key string, s Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[key] = s
+	return nil
+}
+
+func (m *MemoryStore) Expire(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, s := range m.sessions {
+		if s.Expired(now) {
+			delete(m.sessions, key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of sessions held, including expired ones that have
+// not been removed yet.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
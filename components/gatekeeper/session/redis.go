@@ -0,0 +1,104 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisKeyPrefix is prepended to the keys of the sessions in Redis.
+const RedisKeyPrefix = "gatekeeper:session:"
+
+// RedisStore keeps sessions in a server speaking the Redis protocol. Each
+// session is a key that Redis expires together with the session, so Expire
+// has nothing to do.
+type RedisStore struct {
+	pool *redis.Pool
+	now  func() time.Time
+}
+
+// NewRedisStore returns a store of the sessions in the Redis server at
+// address, authenticating with password if it is not empty.
+func NewRedisStore(address, password string) *RedisStore {
+	return &RedisStore{
+		pool: &redis.Pool{
+			MaxIdle:     3,
+			IdleTimeout: 4 * time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", address,
+					redis.DialPassword(password),
+					redis.DialConnectTimeout(5*time.Second),
+					redis.DialReadTimeout(5*time.Second),
+					redis.DialWriteTimeout(5*time.Second))
+			},
+			TestOnBorrow: func(c redis.Conn, t time.Time) error {
+				if time.Since(t) < time.Minute {
+					return nil
+				}
+				_, err := c.Do("PING")
+				return err
+			},
+		},
+		now: time.Now,
+	}
+}
+
+func (r *RedisStore) Get(key string) (Session, bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	value, err := redis.Bytes(conn.Do("GET", RedisKeyPrefix+storageKey(key)))
+	if err == redis.ErrNil {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+	s, err := decode(value)
+	if err != nil {
+		return Session{}, false, err
+	}
+	if s.Expired(r.now()) {
+		return Session{}, false, nil
+	}
+	return s, true, nil
+}
+
+func (r *RedisStore) Put(key string, s Session) error {
+	ttl := s.Expires.Sub(r.now())
+	if ttl <= 0 {
+		return nil
+	}
+	value, err := encode(s)
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+	// Round the expiry up so that Redis never removes a valid session.
+	ms := int64((ttl + time.Millisecond - 1) / time.Millisecond)
+	_, err = conn.Do("SET", RedisKeyPrefix+storageKey(key), value, "PX", ms)
+	return err
+}
+
+func (r *RedisStore) Expire(now time.Time) error {
+	return nil
+}
+
+// Close closes the connections to the server.
+func (r *RedisStore) Close() error {
+	return r.pool.Close()
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package session provides the stores of the login sessions of the
+// gatekeeper. Persistent stores survive restarts of the gatekeeper and can
+// be shared by its replicas.
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Session is a login of a user, identified by the value of its cookie.
+type Session struct {
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Expired returns whether the session is no longer valid at now.
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.Expires)
+}
+
+// Store holds sessions by cookie value.
+type Store interface {
+	// Get returns the session of the cookie value, or false if there is
+	// none or it expired.
+	Get(key string) (Session, bool, error)
+	// Put stores the session under the cookie value.
+	Put(key string, s Session) error
+	// Expire removes the sessions that expired at now.
+	Expire(now time.Time) error
+}
+
+// RunExpiry removes expired sessions from the store every period until stop
+// is closed.
+func RunExpiry(s Store, period time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if err := s.Expire(now); err != nil {
+				log.Errorf("Failed to expire sessions: %v", err)
+			}
+		}
+	}
+}
+
+// storageKey is the key a persistent store keeps a session under. The cookie
+// value is hashed so that reading the store does not reveal valid cookies.
+func storageKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func encode(s Session) ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func decode(data []byte) (Session, error) {
+	var s Session
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
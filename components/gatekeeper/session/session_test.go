@@ -0,0 +1,209 @@
+// Copyright 2019 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var testNow = time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+
+// checkStore checks that a session put into one store can be read from
+// another store of the same backend, as by another replica, and that it
+// disappears once it expires.
+func checkStore(t *testing.T, name string, a, b Store, setNow func(time.Time)) {
+	setNow(testNow)
+	want := Session{Username: "alice", Expires: testNow.Add(time.Hour)}
+	if err := a.Put("cookie1", want); err != nil {
+		t.Fatalf("%s: Put: %v", name, err)
+	}
+	if err := a.Put("cookie2", Session{Username: "bob", Expires: testNow.Add(3 * time.Hour)}); err != nil {
+		t.Fatalf("%s: Put: %v", name, err)
+	}
+
+	got, ok, err := b.Get("cookie1")
+	if err != nil || !ok || got.Username != want.Username || !got.Expires.Equal(want.Expires) {
+		t.Errorf("%s: Get = %+v, %v, %v; want %+v", name, got, ok, err, want)
+	}
+	if _, ok, err := b.Get("unknown"); ok || err != nil {
+		t.Errorf("%s: Get of unknown cookie = %v, %v", name, ok, err)
+	}
+
+	setNow(testNow.Add(2 * time.Hour))
+	if err := b.Expire(testNow.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("%s: Expire: %v", name, err)
+	}
+	for _, s := range []Store{a, b} {
+		if _, ok, _ := s.Get("cookie1"); ok {
+			t.Errorf("%s: expired session still valid", name)
+		}
+		if _, ok, _ := s.Get("cookie2"); !ok {
+			t.Errorf("%s: valid session removed", name)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	m := NewMemoryStore()
+	checkStore(t, "memory", m, m, func(now time.Time) {
+		m.now = func() time.Time { return now }
+	})
+	if m.Len() != 1 {
+		t.Errorf("got %d sessions after expiry, want 1", m.Len())
+	}
+}
+
+// watchedStore waits for the sessions put by other replicas, which a
+// KubeStore only finds once its watch has seen them.
+type watchedStore struct {
+	*KubeStore
+}
+
+func (w watchedStore) Get(key string) (Session, bool, error) {
+	for i := 0; i < 50; i++ {
+		if s, ok, err := w.KubeStore.Get(key); ok || err != nil {
+			return s, ok, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return w.KubeStore.Get(key)
+}
+
+func TestKubeStore(t *testing.T) {
+	for _, kind := range []string{KindSecret, KindConfigMap} {
+		client := fake.NewSimpleClientset()
+		// The fake watches do not replay the changes made before they
+		// started, so the stores are only used once both watch.
+		watches := make(chan struct{}, 10)
+		client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			watches <- struct{}{}
+			return false, nil, nil
+		})
+		stop := make(chan struct{})
+		a, err := NewKubeStore(client, kind, "kubeflow", "sessions")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := NewKubeStore(client, kind, "kubeflow", "sessions")
+		for _, s := range []*KubeStore{a, b} {
+			go s.Run(stop)
+			if err := s.WaitForSync(stop); err != nil {
+				t.Fatal(err)
+			}
+			<-watches
+		}
+		checkStore(t, kind, a, watchedStore{b}, func(now time.Time) {
+			a.cache.now = func() time.Time { return now }
+			b.cache.now = func() time.Time { return now }
+		})
+
+		// Cookies are looked up in the watched object only.
+		client.ClearActions()
+		for _, cookie := range []string{"forged", "cookie2"} {
+			b.Get(cookie)
+		}
+		if actions := client.Actions(); len(actions) != 0 {
+			t.Errorf("%s: Get made API requests %v; want none", kind, actions)
+		}
+		close(stop)
+
+		var keys []string
+		if kind == KindSecret {
+			secret, err := client.CoreV1().Secrets("kubeflow").Get("sessions", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k := range secret.Data {
+				keys = append(keys, k)
+			}
+		} else {
+			cm, err := client.CoreV1().ConfigMaps("kubeflow").Get("sessions", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k := range cm.Data {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) != 1 || keys[0] != storageKey("cookie2") {
+			t.Errorf("%s: got keys %v, want only the hashed key of the valid session", kind, keys)
+		}
+	}
+
+	if _, err := NewKubeStore(fake.NewSimpleClientset(), "Pod", "kubeflow", "sessions"); err == nil {
+		t.Errorf("expected error for unsupported kind")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	a := NewRedisStore(server.Addr(), "")
+	defer a.Close()
+	b := NewRedisStore(server.Addr(), "")
+	defer b.Close()
+	checkStore(t, "redis", a, b, func(now time.Time) {
+		// Expiry is left to Redis, so only move the clock of the server.
+		if now.After(testNow) {
+			server.FastForward(now.Sub(testNow))
+		}
+		a.now = func() time.Time { return testNow }
+		b.now = func() time.Time { return testNow }
+	})
+
+	keys := server.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], RedisKeyPrefix) || strings.Contains(keys[0], "cookie") {
+		t.Errorf("got keys %v, want only the hashed key of the valid session", keys)
+	}
+	if ttl := server.TTL(keys[0]); ttl != time.Hour {
+		t.Errorf("got TTL %v, want %v", ttl, time.Hour)
+	}
+}
+
+func TestRedisStoreAuth(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.RequireAuth("secret")
+
+	wrong := NewRedisStore(server.Addr(), "wrong")
+	defer wrong.Close()
+	if err := wrong.Put("cookie", Session{Username: "alice", Expires: time.Now().Add(time.Hour)}); err == nil {
+		t.Errorf("expected error with wrong password")
+	}
+
+	right := NewRedisStore(server.Addr(), "secret")
+	defer right.Close()
+	if err := right.Put("cookie", Session{Username: "alice", Expires: time.Now().Add(time.Hour)}); err != nil {
+		t.Errorf("Put: %v", err)
+	}
+	if _, ok, err := right.Get("cookie"); !ok || err != nil {
+		t.Errorf("Get = %v, %v", ok, err)
+	}
+}
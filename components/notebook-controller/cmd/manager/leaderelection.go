@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/record"
+	crleaderelection "sigs.k8s.io/controller-runtime/pkg/leaderelection"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// leaderElectionOptions configures the leader election between the replicas
+// of the manager. The leader election of controller-runtime has fixed
+// durations, so the manager runs its own.
+type leaderElectionOptions struct {
+	Enabled bool
+	// Namespace and ID of the ConfigMap that holds the lock. The namespace
+	// defaults to the one of the manager Pod.
+	Namespace string
+	ID        string
+	// LeaseDuration is how long the other replicas wait before taking over
+	// from a leader that stopped renewing its lease.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries to renew its lease before
+	// it stops leading.
+	RenewDeadline time.Duration
+	// RetryPeriod is how often the replicas try to acquire or renew the lease.
+	RetryPeriod time.Duration
+}
+
+var defaultLeaderElectionOptions = leaderElectionOptions{
+	ID:            "notebook-controller-leader-election",
+	LeaseDuration: 15 * time.Second,
+	RenewDeadline: 10 * time.Second,
+	RetryPeriod:   2 * time.Second,
+}
+
+// AddFlags registers the leader election options with fs.
+func (o *leaderElectionOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "enable-leader-election", o.Enabled, "Elect a leader among the replicas of the manager. Only the leader reconciles; all replicas serve the webhooks.")
+	fs.StringVar(&o.Namespace, "leader-election-namespace", o.Namespace, "The namespace of the leader election ConfigMap. Defaults to the namespace of the manager Pod.")
+	fs.StringVar(&o.ID, "leader-election-id", o.ID, "The name of the leader election ConfigMap.")
+	fs.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", o.LeaseDuration, "How long the other replicas wait before taking over from a leader that stopped renewing its lease.")
+	fs.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", o.RenewDeadline, "How long the leader retries to renew its lease before it stops leading.")
+	fs.DurationVar(&o.RetryPeriod, "leader-election-retry-period", o.RetryPeriod, "How often the replicas try to acquire or renew the lease.")
+}
+
+// run calls run once the process is elected leader, and blocks until stop is
+// closed or the process stops leading. The managers of controller-runtime can't
+// be restarted, so it returns an error when leadership is lost, and the
+// process exits to run for the lease again.
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update;patch
+func (o *leaderElectionOptions) run(cfg *rest.Config, mgr manager.Manager, stop <-chan struct{}, run func(stop <-chan struct{}) error) error {
+	lock, err := crleaderelection.NewResourceLock(cfg, recorderProvider{mgr}, crleaderelection.Options{
+		LeaderElection:          true,
+		LeaderElectionNamespace: o.Namespace,
+		LeaderElectionID:        o.ID,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	leading := make(chan context.Context, 1)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: o.LeaseDuration,
+		RenewDeadline: o.RenewDeadline,
+		RetryPeriod:   o.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				leading <- ctx
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+
+	log.Info("Waiting to be elected leader", "namespace", o.Namespace, "id", o.ID)
+	select {
+	case leaderCtx := <-leading:
+		log.Info("Elected leader")
+		err = run(leaderCtx.Done())
+		cancel()
+		<-done
+	case <-done:
+	}
+	if err != nil {
+		return err
+	}
+	select {
+	case <-stop:
+		return nil
+	default:
+		return fmt.Errorf("lost the leader election lease")
+	}
+}
+
+// recorderProvider records the leader election events with the recorder of the manager.
+type recorderProvider struct {
+	mgr manager.Manager
+}
+
+func (p recorderProvider) GetEventRecorderFor(name string) record.EventRecorder {
+	return p.mgr.GetRecorder(name)
+}
@@ -17,25 +17,68 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
 	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/controller"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/controller/notebook"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/controller/prepull"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/health"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/migration"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/shutdown"
 	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/webhook"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/webhook/default_server/notebook/conversion"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 )
 
+var log = logf.Log.WithName("entrypoint")
+
 func main() {
 	var metricsAddr string
+	var probeAddr string
+	var maxReconcileAge time.Duration
+	var migrateStorageVersion bool
+	var enableWebhooks bool
+	var namespaces string
+	var shutdownTimeout time.Duration
+	leaderElection := defaultLeaderElectionOptions
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-addr", ":8081", "The address the /healthz and /readyz probe endpoints bind to.")
+	flag.DurationVar(&maxReconcileAge, "max-reconcile-age", 10*time.Minute, "How long a reconcile may run before the manager is unhealthy.")
+	flag.BoolVar(&migrateStorageVersion, "migrate-storage-version", false, "Rewrite the stored Notebooks in the v1beta1 storage version.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true, "Serve the admission and conversion webhooks.")
+	flag.StringVar(&namespaces, "namespace", "", "Comma-separated namespaces to reconcile notebooks in. All namespaces if empty.")
+	flag.DurationVar(&shutdownTimeout, "graceful-shutdown-timeout", 5*time.Second, "How long to wait for running reconciles when the manager is stopped.")
+	leaderElection.AddFlags(flag.CommandLine)
+	notebook.DefaultOptions.AddFlags(flag.CommandLine)
+	prepull.DefaultOptions.AddFlags(flag.CommandLine)
 	flag.Parse()
 	logf.SetLogger(logf.ZapLogger(false))
-	log := logf.Log.WithName("entrypoint")
+
+	if migrateStorageVersion && !enableWebhooks {
+		log.Error(fmt.Errorf("--migrate-storage-version needs the conversion webhook"), "invalid flags")
+		os.Exit(1)
+	}
+	// The images of the notebooks of all namespaces are pre-pulled, by a
+	// single DaemonSet.
+	if prepull.DefaultOptions.Namespace != "" && namespaces != "" {
+		log.Error(fmt.Errorf("--prepull-namespace needs a manager for all namespaces"), "invalid flags")
+		os.Exit(1)
+	}
 
 	// Get a config to talk to the apiserver
 	log.Info("setting up client for manager")
@@ -45,40 +88,212 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create a new Cmd to provide shared dependencies and start components
-	log.Info("setting up manager")
-	mgr, err := manager.New(cfg, manager.Options{MetricsBindAddress: metricsAddr})
-	if err != nil {
-		log.Error(err, "unable to set up overall controller manager")
-		os.Exit(1)
+	// The cache of a manager watches one namespace or all of them, so there is
+	// a manager per namespace for the controllers. The webhooks serve all
+	// namespaces and get a manager of their own, which runs on all replicas
+	// whether they lead or not.
+	log.Info("setting up managers")
+	var managers []manager.Manager
+	// The namespaces watched by the managers, "" for all namespaces.
+	var managerNamespaces []string
+	for _, ns := range splitNamespaces(namespaces) {
+		mgr, err := newManager(cfg, ns)
+		if err != nil {
+			log.Error(err, "unable to set up overall controller manager", "namespace", ns)
+			os.Exit(1)
+		}
+		log.Info("Setting up controller", "namespace", ns)
+		if err := controller.AddToManager(mgr); err != nil {
+			log.Error(err, "unable to register controllers to the manager")
+			os.Exit(1)
+		}
+		managers = append(managers, mgr)
+		managerNamespaces = append(managerNamespaces, ns)
+	}
+	// The runnables that only run on the leader.
+	var leading []manager.Runnable
+	for _, mgr := range managers {
+		leading = append(leading, mgr)
 	}
 
-	log.Info("Registering Components.")
+	var webhookMgr manager.Manager
+	if enableWebhooks {
+		log.Info("setting up webhooks")
+		if webhookMgr, err = newManager(cfg, ""); err != nil {
+			log.Error(err, "unable to set up the manager of the webhooks")
+			os.Exit(1)
+		}
+		if err := webhook.AddToManager(webhookMgr); err != nil {
+			log.Error(err, "unable to register webhooks to the manager")
+			os.Exit(1)
+		}
+		// The API server must trust the conversion webhook to serve any
+		// version of the Notebooks but the storage version. The webhook server
+		// writes its certificates to /tmp/cert.
+		syncer := &conversion.CABundleSyncer{
+			Client:  webhookMgr.GetClient(),
+			CertDir: "/tmp/cert",
+			Period:  time.Minute,
+		}
+		if err := webhookMgr.Add(syncer); err != nil {
+			log.Error(err, "unable to register the CA bundle sync of the conversion webhook")
+			os.Exit(1)
+		}
+	}
 
-	// Setup Scheme for all resources
-	log.Info("setting up scheme")
-	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
-		log.Error(err, "unable add APIs to scheme")
-		os.Exit(1)
+	if migrateStorageVersion {
+		log.Info("setting up storage version migration")
+		leading = append(leading, &migration.Migrator{
+			Client:      webhookMgr.GetClient(),
+			RetryPeriod: 10 * time.Second,
+		})
 	}
 
-	// Setup all Controllers
-	log.Info("Setting up controller")
-	if err := controller.AddToManager(mgr); err != nil {
-		log.Error(err, "unable to register controllers to the manager")
-		os.Exit(1)
+	stop := signals.SetupSignalHandler()
+
+	// The manager is alive as long as its reconciles don't keep failing or
+	// hang, and ready once its caches have synced and it can serve the
+	// webhooks. The caches of the controllers only start on the leader, so
+	// with leader election they don't keep the other replicas from serving
+	// the webhooks.
+	healthz := &health.Handler{}
+	healthz.AddCheck("ping", health.Ping)
+	healthz.AddCheck("reconciles", health.DefaultReconciles.Check(maxReconcileAge))
+	readyz := &health.Handler{}
+	if !leaderElection.Enabled {
+		for i, mgr := range managers {
+			name := "cache"
+			if ns := managerNamespaces[i]; ns != "" {
+				name = "cache-" + ns
+			}
+			readyz.AddCheck(name, health.CacheSynced(mgr.GetCache(), stop))
+		}
 	}
+	if enableWebhooks {
+		readyz.AddCheck("cache-webhooks", health.CacheSynced(webhookMgr.GetCache(), stop))
+		readyz.AddCheck("webhook-cert", health.ServingCertValid("/tmp/cert", time.Now))
+	}
+	probes := http.NewServeMux()
+	probes.Handle("/healthz", healthz)
+	probes.Handle("/readyz", readyz)
 
-	log.Info("setting up webhooks")
-	if err := webhook.AddToManager(mgr); err != nil {
-		log.Error(err, "unable to register webhooks to the manager")
+	// Metrics and probes are served whether the manager is the leader or not.
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	}))
+	if err := serve(metricsAddr, metricsMux, stop); err != nil {
+		log.Error(err, "unable to serve metrics")
+		os.Exit(1)
+	}
+	if err := serve(probeAddr, probes, stop); err != nil {
+		log.Error(err, "unable to serve the health probes")
 		os.Exit(1)
 	}
 
 	// Start the Cmd
 	log.Info("Starting the Cmd.")
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	leader := runnableFunc(func(stop <-chan struct{}) error {
+		return runAll(leading, stop)
+	})
+	if leaderElection.Enabled {
+		lead := leader
+		leader = func(stop <-chan struct{}) error {
+			return leaderElection.run(cfg, managers[0], stop, lead)
+		}
+	}
+	runnables := []manager.Runnable{leader}
+	if webhookMgr != nil {
+		runnables = append(runnables, webhookMgr)
+	}
+	err = runAll(runnables, stop)
+	log.Info("Waiting for running reconciles")
+	if !shutdown.Drain(shutdownTimeout) {
+		log.Info("Timed out waiting for running reconciles", "timeout", shutdownTimeout)
+	}
+	if err != nil {
 		log.Error(err, "unable to run the manager")
 		os.Exit(1)
 	}
 }
+
+// splitNamespaces returns the namespaces of the comma-separated list, or the
+// empty namespace that stands for all of them.
+func splitNamespaces(list string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		return []string{""}
+	}
+	return namespaces
+}
+
+// newManager returns a manager whose cache watches namespace, or all
+// namespaces if it is empty. Metrics are served by serveMetrics for all managers.
+func newManager(cfg *rest.Config, namespace string) (manager.Manager, error) {
+	mgr, err := manager.New(cfg, manager.Options{MetricsBindAddress: "0", Namespace: namespace})
+	if err != nil {
+		return nil, err
+	}
+	// Setup Scheme for all resources
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// runnableFunc is a function that implements manager.Runnable.
+type runnableFunc func(stop <-chan struct{}) error
+
+func (f runnableFunc) Start(stop <-chan struct{}) error {
+	return f(stop)
+}
+
+// runAll starts the runnables, e.g. managers, and blocks until stop is closed
+// or one of them fails, and then stops the others.
+func runAll(runnables []manager.Runnable, stop <-chan struct{}) error {
+	errs := make(chan error, len(runnables))
+	runnablesStop := make(chan struct{})
+	defer close(runnablesStop)
+	for _, r := range runnables {
+		go func(r manager.Runnable) {
+			errs <- r.Start(runnablesStop)
+		}(r)
+	}
+	select {
+	case <-stop:
+		return nil
+	case err := <-errs:
+		if err == nil {
+			err = fmt.Errorf("manager stopped unexpectedly")
+		}
+		return err
+	}
+}
+
+// serve serves handler on addr until stop is closed. The address "0" disables
+// the server.
+func serve(addr string, handler http.Handler, stop <-chan struct{}) error {
+	if addr == "0" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler}
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Error(err, "server failed", "addr", addr)
+		}
+	}()
+	go func() {
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+	return nil
+}
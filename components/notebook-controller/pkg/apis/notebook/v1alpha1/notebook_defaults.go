@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultWorkingDir is the home directory of the user in the Jupyter images.
+const DefaultWorkingDir = "/home/jovyan"
+
+// DefaultContainerPort is the port the Jupyter server listens on.
+const DefaultContainerPort = 8888
+
+// DefaultContainerPortName is the name of the default notebook container port.
+const DefaultContainerPortName = "notebook-port"
+
+// The default fsGroup of PodSecurityContext.
+// https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.11/#podsecuritycontext-v1-core
+const DefaultFSGroup = int64(100)
+
+// SetNotebookDefaults fills in the unset fields of the notebook's PodSpec and workspace.
+// The PodSpec of a notebook created from a template or cloned from a snapshot
+// only overrides the one of the template or snapshot, so its defaults are set by
+// the controller once they are merged.
+func SetNotebookDefaults(nb *Notebook) {
+	if ws := nb.Spec.Workspace; ws != nil {
+		if ws.AccessMode == "" {
+			ws.AccessMode = corev1.ReadWriteOnce
+		}
+		if ws.MountPath == "" {
+			ws.MountPath = DefaultWorkingDir
+		}
+		if ws.RetainPolicy == "" {
+			ws.RetainPolicy = WorkspaceRetain
+		}
+	}
+
+	if nb.Spec.TemplateRef == nil && nb.Spec.CloneFrom == "" {
+		SetPodSpecDefaults(&nb.Spec.Template.Spec)
+	}
+}
+
+// SetPodSpecDefaults fills in the unset fields of a notebook PodSpec.
+// The first container is the notebook container.
+func SetPodSpecDefaults(podSpec *corev1.PodSpec) {
+	if len(podSpec.Containers) == 0 {
+		return
+	}
+	container := &podSpec.Containers[0]
+	if container.WorkingDir == "" {
+		container.WorkingDir = DefaultWorkingDir
+	}
+	if container.Ports == nil {
+		container.Ports = []corev1.ContainerPort{
+			corev1.ContainerPort{
+				ContainerPort: DefaultContainerPort,
+				Name:          DefaultContainerPortName,
+				Protocol:      "TCP",
+			},
+		}
+	}
+	if podSpec.SecurityContext == nil {
+		fsGroup := DefaultFSGroup
+		podSpec.SecurityContext = &corev1.PodSecurityContext{
+			FSGroup: &fsGroup,
+		}
+	}
+}
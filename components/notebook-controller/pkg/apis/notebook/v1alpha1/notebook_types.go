@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,35 +26,195 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // NotebookSpec defines the desired state of Notebook
+// All the fields are also in v1beta1, which adds fields for the notebook
+// container and the volumes, set in Template here, and for the culling, set
+// with annotations here.
 type NotebookSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// Template is the Pod template of the notebook, whose first container is
+	// the notebook container. With a templateRef or cloneFrom, it only sets
+	// the fields to override.
 	Template NotebookTemplateSpec `json:"template,omitempty"`
+	// TemplateRef refers to a NotebookTemplate or ClusterNotebookTemplate the
+	// Pod spec is merged into. Template then only sets the fields to override.
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+	// Workspace is an optional persistent volume managed by the controller and
+	// mounted into the notebook container.
+	Workspace *WorkspaceSpec `json:"workspace,omitempty"`
+	// Stopped scales the notebook down to zero replicas. Its StatefulSet, Service
+	// and workspace are kept, so it resumes with the same identity and volume when
+	// Stopped is unset. Changes to the template while stopped are applied on resume.
+	Stopped bool `json:"stopped,omitempty"`
+	// CloneFrom is the name of a NotebookSnapshot of the namespace to restore
+	// the notebook from. The Pod spec is merged into the one of the snapshot,
+	// and the workspace is restored from its VolumeSnapshot.
+	CloneFrom string `json:"cloneFrom,omitempty"`
+	// ExposedPorts are the names of container ports of the notebook, e.g. of
+	// TensorBoard, to publish besides the notebook server. Each gets a port on
+	// the notebook Service and a route under /notebook/<namespace>/<name>/ports/<port name>/.
+	ExposedPorts []string `json:"exposedPorts,omitempty"`
+	// ServiceAccount makes the controller create a ServiceAccount for the
+	// notebook Pod, with its own permissions. The Pod runs as the ServiceAccount
+	// of its spec, by default the one of the namespace, if it is nil.
+	ServiceAccount *ServiceAccountSpec `json:"serviceAccount,omitempty"`
+	// Schedule runs the notebook only within recurring windows, e.g. on
+	// weekdays from 08:00 to 20:00. It is scaled down outside of them.
+	// Stopped takes precedence over the schedule.
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+	// DisableDefaultProbes keeps the controller from adding readiness and
+	// liveness probes of the Jupyter API to the notebook container, e.g. for
+	// images that don't run Jupyter. Probes set in the container are always kept.
+	DisableDefaultProbes bool `json:"disableDefaultProbes,omitempty"`
+}
+
+// ScheduleSpec describes the windows in which a notebook runs with cron
+// expressions of the standard five fields, e.g. "0 8 * * MON-FRI". The notebook
+// runs from a time matching Start to the next time matching Stop.
+type ScheduleSpec struct {
+	// Start is the cron expression of the times the notebook is started.
+	Start string `json:"start"`
+	// Stop is the cron expression of the times the notebook is stopped.
+	Stop string `json:"stop"`
+	// TimeZone of the expressions, an IANA name like Europe/Berlin. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ServiceAccountSpec describes the ServiceAccount the controller creates for
+// the notebook. The ServiceAccount, and its Role and RoleBinding, are named
+// notebook-<name> and are garbage collected with the notebook. The
+// ServiceAccount overrides the serviceAccountName of the Pod spec.
+type ServiceAccountSpec struct {
+	// RoleTemplate is the name of a ClusterRole labeled
+	// notebooks.kubeflow.org/role-template=true whose rules are granted to the
+	// ServiceAccount in the namespace of the notebook. The ServiceAccount has
+	// no permissions if it is empty.
+	RoleTemplate string `json:"roleTemplate,omitempty"`
 }
 
 type NotebookTemplateSpec struct {
 	Spec corev1.PodSpec `json:"spec,omitempty"`
 }
 
+// WorkspaceSpec describes the persistent volume that holds the work of the notebook.
+type WorkspaceSpec struct {
+	// Size of the volume, e.g. 10Gi.
+	Size resource.Quantity `json:"size"`
+	// StorageClassName of the volume. The default storage class of the cluster
+	// is used if it is empty.
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// AccessMode of the volume. Defaults to ReadWriteOnce.
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+	// MountPath of the volume in the notebook container. Defaults to /home/jovyan.
+	MountPath string `json:"mountPath,omitempty"`
+	// RetainPolicy says what happens to the volume when the notebook is deleted.
+	// Defaults to Retain.
+	RetainPolicy WorkspaceRetainPolicy `json:"retainPolicy,omitempty"`
+}
+
+type WorkspaceRetainPolicy string
+
+const (
+	// WorkspaceRetain keeps the volume when the notebook is deleted.
+	WorkspaceRetain WorkspaceRetainPolicy = "Retain"
+	// WorkspaceDelete deletes the volume together with the notebook.
+	WorkspaceDelete WorkspaceRetainPolicy = "Delete"
+)
+
+// RoleTemplateLabel set to "true" marks the ClusterRoles that notebooks may use
+// as the RoleTemplate of their ServiceAccount. The controller copies their rules
+// into a Role, so only ClusterRoles picked by the cluster admin are allowed.
+const RoleTemplateLabel = "notebooks.kubeflow.org/role-template"
+
+// The culling policy of a notebook is set with annotations in v1alpha1.
+// It is a typed field in later versions.
+const (
+	// CullingDisabledAnnotation set to "true" exempts the notebook from culling.
+	CullingDisabledAnnotation = "notebooks.kubeflow.org/culling-disabled"
+	// IdleTimeAnnotation overrides the idle time of the controller for the notebook, e.g. 2h0m0s.
+	IdleTimeAnnotation = "notebooks.kubeflow.org/idle-time"
+)
+
 // NotebookStatus defines the observed state of Notebook
 type NotebookStatus struct {
 	// Conditions is an array of current conditions
 	Conditions []NotebookCondition `json:"conditions"`
+	// ReadyReplicas is the number of Pods created by the StatefulSet controller that have a Ready Condition.
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// ContainerState is the state of the notebook container in the Pod.
+	ContainerState corev1.ContainerState `json:"containerState"`
+	// URLs are the URLs the notebook serves users on, the notebook server first.
+	URLs []NotebookURL `json:"urls,omitempty"`
+	// Schedule is the state of the schedule of the notebook, if it has one.
+	Schedule *ScheduleStatus `json:"schedule,omitempty"`
+}
+
+// ScheduleStatus is the state of the schedule of a notebook.
+type ScheduleStatus struct {
+	// Running is true within a window of the schedule.
+	Running bool `json:"running"`
+	// NextTransition is the time the notebook is next started, or stopped if
+	// it is running.
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+}
+
+// NotebookURL is a URL the notebook serves users on.
+type NotebookURL struct {
+	// Name is "notebook" for the notebook server, or the name of the exposed port.
+	Name string `json:"name"`
+	// URL is the path of the URL on the hosts of the routing provider, e.g.
+	// /notebook/<namespace>/<name>/.
+	URL string `json:"url"`
 }
 
 type NotebookCondition struct {
 	// Type of the confition/
 	Type NotebookConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status,omitempty"`
+	// Last time we probed the condition.
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// (brief) reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// Human readable message indicating details about last transition.
+	Message string `json:"message,omitempty"`
 }
 
 type NotebookConditionType string
 
+const (
+	// NotebookCulled is true when the notebook has been scaled to zero
+	// because it was idle for longer than the configured idle time.
+	NotebookCulled NotebookConditionType = "Culled"
+	// NotebookReady is true when the notebook Pod is ready to serve requests.
+	// When it is not, the reason explains why, e.g. ImagePullBackOff.
+	NotebookReady NotebookConditionType = "Ready"
+	// NotebookWorkspaceReady is true when the workspace volume is bound.
+	NotebookWorkspaceReady NotebookConditionType = "WorkspaceReady"
+	// NotebookStopped is true when a notebook with spec.stopped set has been
+	// scaled down. It is false with reason Stopping while the Pod terminates.
+	NotebookStopped NotebookConditionType = "Stopped"
+	// NotebookPolicyViolated is true when the notebook violates a NotebookPolicy
+	// of its namespace, e.g. because the policy was created after the notebook.
+	NotebookPolicyViolated NotebookConditionType = "PolicyViolated"
+	// NotebookCleanupFailed is true when a cleanup hook failed for a deleted
+	// notebook. The message gives the hook and its error. The notebook is kept
+	// until the hooks succeed.
+	NotebookCleanupFailed NotebookConditionType = "CleanupFailed"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // Notebook is the Schema for the notebooks API
 // +k8s:openapi-gen=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Notebook struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
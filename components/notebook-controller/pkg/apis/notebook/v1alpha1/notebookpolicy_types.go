@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotebookPolicySpec limits the notebooks of a namespace. Unset fields don't limit anything.
+type NotebookPolicySpec struct {
+	// MaxNotebooks is the maximum number of notebooks in the namespace.
+	MaxNotebooks *int32 `json:"maxNotebooks,omitempty"`
+	// MaxResources is the maximum of the requests and of the limits of each
+	// notebook, summed over its containers, e.g. cpu: 4 and memory: 16Gi.
+	// Like with a ResourceQuota, notebooks must set limits for these resources.
+	MaxResources corev1.ResourceList `json:"maxResources,omitempty"`
+	// AllowedImages are the images the containers of notebooks may run. They are
+	// patterns matched with path.Match, e.g. gcr.io/kubeflow-images-public/*.
+	AllowedImages []string `json:"allowedImages,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookPolicy limits the number of notebooks of a namespace, their resources
+// and their images. The validating webhook rejects notebooks that violate a
+// policy of their namespace, and the controller reports the violations of
+// existing notebooks in their PolicyViolated condition.
+// +k8s:openapi-gen=true
+type NotebookPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotebookPolicySpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookPolicyList contains a list of NotebookPolicy
+type NotebookPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotebookPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotebookPolicy{}, &NotebookPolicyList{})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotebookSnapshotSpec defines the notebook a NotebookSnapshot captures.
+type NotebookSnapshotSpec struct {
+	// NotebookName is the name of the notebook to capture, in the namespace of the snapshot.
+	NotebookName string `json:"notebookName"`
+	// VolumeSnapshotClassName is the class of the VolumeSnapshot of the workspace.
+	// The default class of the CSI driver is used if it is empty.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// NotebookSnapshotStatus holds the captured notebook.
+type NotebookSnapshotStatus struct {
+	// NotebookSpec is the spec of the notebook when it was captured. The PodSpec
+	// of its template is merged into Template, so it doesn't depend on templates
+	// that may change later.
+	NotebookSpec *NotebookSpec `json:"notebookSpec,omitempty"`
+	// CaptureTime is when the notebook was captured.
+	CaptureTime *metav1.Time `json:"captureTime,omitempty"`
+	// VolumeSnapshotName is the name of the VolumeSnapshot of the workspace.
+	// It is empty if the notebook has no workspace.
+	VolumeSnapshotName string `json:"volumeSnapshotName,omitempty"`
+	// ReadyToUse is true when notebooks can be cloned from the snapshot, that is
+	// when the notebook has been captured and its VolumeSnapshot is ready.
+	ReadyToUse bool `json:"readyToUse"`
+	// Message explains why the snapshot isn't ready, e.g. the error of its VolumeSnapshot.
+	Message string `json:"message,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookSnapshot is a point-in-time copy of a notebook: its spec and a
+// VolumeSnapshot of its workspace. Notebooks are restored from it with
+// spec.cloneFrom. A snapshot is taken once; changing it has no effect.
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Notebook",type="string",JSONPath=".spec.notebookName"
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.readyToUse"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type NotebookSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotebookSnapshotSpec   `json:"spec,omitempty"`
+	Status NotebookSnapshotStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookSnapshotList contains a list of NotebookSnapshot
+type NotebookSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotebookSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotebookSnapshot{}, &NotebookSnapshotList{})
+}
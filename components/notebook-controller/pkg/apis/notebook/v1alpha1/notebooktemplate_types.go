@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The kinds of templates a notebook can reference.
+const (
+	NotebookTemplateKind        = "NotebookTemplate"
+	ClusterNotebookTemplateKind = "ClusterNotebookTemplate"
+)
+
+// TemplateReference refers to the NotebookTemplate or ClusterNotebookTemplate
+// a notebook is created from.
+type TemplateReference struct {
+	// Kind of the template, NotebookTemplate or ClusterNotebookTemplate.
+	// Defaults to NotebookTemplate.
+	Kind string `json:"kind,omitempty"`
+	// Name of the template. A NotebookTemplate must be in the namespace of the notebook.
+	Name string `json:"name"`
+}
+
+// NotebookTemplateDefinition defines the Pod of the notebooks created from a
+// template, e.g. a blessed image, resource presets and volumes.
+type NotebookTemplateDefinition struct {
+	// Description of the template for users choosing one.
+	Description string `json:"description,omitempty"`
+	// Template is the Pod spec of the notebooks. The fields set in the template
+	// of a notebook override it; containers and volumes are merged by name.
+	Template NotebookTemplateSpec `json:"template"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookTemplate is a template for the notebooks of a namespace.
+// +k8s:openapi-gen=true
+type NotebookTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotebookTemplateDefinition `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NotebookTemplateList contains a list of NotebookTemplate
+type NotebookTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotebookTemplate `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterNotebookTemplate is a template for the notebooks of all namespaces.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path=clusternotebooktemplates,scope=Cluster
+type ClusterNotebookTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotebookTemplateDefinition `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterNotebookTemplateList contains a list of ClusterNotebookTemplate
+type ClusterNotebookTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterNotebookTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotebookTemplate{}, &NotebookTemplateList{})
+	SchemeBuilder.Register(&ClusterNotebookTemplate{}, &ClusterNotebookTemplateList{})
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The conversions are lossless: converting a v1alpha1 Notebook to v1beta1 and
+// back gives the original object. The fields of the first container that are
+// typed in v1beta1 are moved out of the template, and the culling annotations
+// of v1alpha1 become the culling policy.
+
+// Convert_v1alpha1_Notebook_To_v1beta1_Notebook converts a v1alpha1 Notebook to v1beta1.
+func Convert_v1alpha1_Notebook_To_v1beta1_Notebook(in *v1alpha1.Notebook, out *Notebook) error {
+	in = in.DeepCopy()
+	out.TypeMeta = in.TypeMeta
+	out.APIVersion = SchemeGroupVersion.String()
+	out.ObjectMeta = in.ObjectMeta
+	out.Spec = NotebookSpec{}
+	out.Status = NotebookStatus{}
+
+	podSpec := in.Spec.Template.Spec
+	if len(podSpec.Containers) > 0 {
+		c := &podSpec.Containers[0]
+		out.Spec.Image, c.Image = c.Image, ""
+		out.Spec.Command, c.Command = c.Command, nil
+		out.Spec.Args, c.Args = c.Args, nil
+		out.Spec.Resources, c.Resources = c.Resources, corev1.ResourceRequirements{}
+		out.Spec.Env, c.Env = c.Env, nil
+	}
+	out.Spec.Volumes, podSpec.Volumes = podSpec.Volumes, nil
+	out.Spec.Template.Spec = podSpec
+	out.Spec.Stopped = in.Spec.Stopped
+	if ref := in.Spec.TemplateRef; ref != nil {
+		out.Spec.TemplateRef = &TemplateReference{Kind: ref.Kind, Name: ref.Name}
+	}
+	out.Spec.CloneFrom = in.Spec.CloneFrom
+	out.Spec.ExposedPorts = in.Spec.ExposedPorts
+	if sa := in.Spec.ServiceAccount; sa != nil {
+		out.Spec.ServiceAccount = &ServiceAccountSpec{RoleTemplate: sa.RoleTemplate}
+	}
+	if sc := in.Spec.Schedule; sc != nil {
+		out.Spec.Schedule = &ScheduleSpec{Start: sc.Start, Stop: sc.Stop, TimeZone: sc.TimeZone}
+	}
+	out.Spec.DisableDefaultProbes = in.Spec.DisableDefaultProbes
+
+	out.Spec.Culling = cullingFromAnnotations(out.Annotations)
+	if p := out.Spec.Culling; p != nil {
+		if p.Disabled {
+			delete(out.Annotations, v1alpha1.CullingDisabledAnnotation)
+		}
+		if p.IdleTime != nil {
+			delete(out.Annotations, v1alpha1.IdleTimeAnnotation)
+		}
+		if len(out.Annotations) == 0 {
+			out.Annotations = nil
+		}
+	}
+
+	if ws := in.Spec.Workspace; ws != nil {
+		out.Spec.Workspace = &WorkspaceSpec{
+			Size:             ws.Size,
+			StorageClassName: ws.StorageClassName,
+			AccessMode:       ws.AccessMode,
+			MountPath:        ws.MountPath,
+			RetainPolicy:     WorkspaceRetainPolicy(ws.RetainPolicy),
+		}
+	}
+
+	out.Status.ReadyReplicas = in.Status.ReadyReplicas
+	out.Status.ContainerState = in.Status.ContainerState
+	if in.Status.URLs != nil {
+		out.Status.URLs = make([]NotebookURL, len(in.Status.URLs))
+		for i, u := range in.Status.URLs {
+			out.Status.URLs[i] = NotebookURL{Name: u.Name, URL: u.URL}
+		}
+	}
+	if sc := in.Status.Schedule; sc != nil {
+		out.Status.Schedule = &ScheduleStatus{Running: sc.Running, NextTransition: sc.NextTransition}
+	}
+	if in.Status.Conditions != nil {
+		out.Status.Conditions = make([]NotebookCondition, len(in.Status.Conditions))
+		for i, c := range in.Status.Conditions {
+			out.Status.Conditions[i] = NotebookCondition{
+				Type:               NotebookConditionType(c.Type),
+				Status:             c.Status,
+				LastProbeTime:      c.LastProbeTime,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			}
+		}
+	}
+	return nil
+}
+
+// Convert_v1beta1_Notebook_To_v1alpha1_Notebook converts a v1beta1 Notebook to v1alpha1.
+// If the template has no containers, the notebook container is named after the notebook.
+func Convert_v1beta1_Notebook_To_v1alpha1_Notebook(in *Notebook, out *v1alpha1.Notebook) error {
+	in = in.DeepCopy()
+	out.TypeMeta = in.TypeMeta
+	out.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	out.ObjectMeta = in.ObjectMeta
+	out.Spec = v1alpha1.NotebookSpec{}
+	out.Status = v1alpha1.NotebookStatus{}
+
+	podSpec := in.Spec.Template.Spec
+	if len(podSpec.Containers) == 0 && hasContainerFields(&in.Spec) {
+		podSpec.Containers = []corev1.Container{{Name: in.Name}}
+	}
+	if len(podSpec.Containers) > 0 {
+		c := &podSpec.Containers[0]
+		c.Image = in.Spec.Image
+		c.Command = in.Spec.Command
+		c.Args = in.Spec.Args
+		c.Resources = in.Spec.Resources
+		c.Env = in.Spec.Env
+	}
+	if in.Spec.Volumes != nil {
+		podSpec.Volumes = append(in.Spec.Volumes, podSpec.Volumes...)
+	}
+	out.Spec.Template.Spec = podSpec
+	out.Spec.Stopped = in.Spec.Stopped
+	if ref := in.Spec.TemplateRef; ref != nil {
+		out.Spec.TemplateRef = &v1alpha1.TemplateReference{Kind: ref.Kind, Name: ref.Name}
+	}
+	out.Spec.CloneFrom = in.Spec.CloneFrom
+	out.Spec.ExposedPorts = in.Spec.ExposedPorts
+	if sa := in.Spec.ServiceAccount; sa != nil {
+		out.Spec.ServiceAccount = &v1alpha1.ServiceAccountSpec{RoleTemplate: sa.RoleTemplate}
+	}
+	if sc := in.Spec.Schedule; sc != nil {
+		out.Spec.Schedule = &v1alpha1.ScheduleSpec{Start: sc.Start, Stop: sc.Stop, TimeZone: sc.TimeZone}
+	}
+	out.Spec.DisableDefaultProbes = in.Spec.DisableDefaultProbes
+
+	if p := in.Spec.Culling; p != nil {
+		if out.Annotations == nil {
+			out.Annotations = map[string]string{}
+		}
+		if p.Disabled {
+			out.Annotations[v1alpha1.CullingDisabledAnnotation] = "true"
+		}
+		if p.IdleTime != nil {
+			out.Annotations[v1alpha1.IdleTimeAnnotation] = p.IdleTime.Duration.String()
+		}
+	}
+
+	if ws := in.Spec.Workspace; ws != nil {
+		out.Spec.Workspace = &v1alpha1.WorkspaceSpec{
+			Size:             ws.Size,
+			StorageClassName: ws.StorageClassName,
+			AccessMode:       ws.AccessMode,
+			MountPath:        ws.MountPath,
+			RetainPolicy:     v1alpha1.WorkspaceRetainPolicy(ws.RetainPolicy),
+		}
+	}
+
+	out.Status.ReadyReplicas = in.Status.ReadyReplicas
+	out.Status.ContainerState = in.Status.ContainerState
+	if in.Status.URLs != nil {
+		out.Status.URLs = make([]v1alpha1.NotebookURL, len(in.Status.URLs))
+		for i, u := range in.Status.URLs {
+			out.Status.URLs[i] = v1alpha1.NotebookURL{Name: u.Name, URL: u.URL}
+		}
+	}
+	if sc := in.Status.Schedule; sc != nil {
+		out.Status.Schedule = &v1alpha1.ScheduleStatus{Running: sc.Running, NextTransition: sc.NextTransition}
+	}
+	if in.Status.Conditions != nil {
+		out.Status.Conditions = make([]v1alpha1.NotebookCondition, len(in.Status.Conditions))
+		for i, c := range in.Status.Conditions {
+			out.Status.Conditions[i] = v1alpha1.NotebookCondition{
+				Type:               v1alpha1.NotebookConditionType(c.Type),
+				Status:             c.Status,
+				LastProbeTime:      c.LastProbeTime,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			}
+		}
+	}
+	return nil
+}
+
+// cullingFromAnnotations returns the culling policy set by the v1alpha1 annotations.
+// Only values that convert back to the same annotation are used, the others
+// are left as annotations so the conversion stays lossless.
+func cullingFromAnnotations(annotations map[string]string) *CullingPolicy {
+	var p *CullingPolicy
+	if annotations[v1alpha1.CullingDisabledAnnotation] == "true" {
+		p = &CullingPolicy{Disabled: true}
+	}
+	if v, ok := annotations[v1alpha1.IdleTimeAnnotation]; ok {
+		if d, err := time.ParseDuration(v); err == nil && d.String() == v {
+			if p == nil {
+				p = &CullingPolicy{}
+			}
+			p.IdleTime = &metav1.Duration{Duration: d}
+		}
+	}
+	return p
+}
+
+// hasContainerFields returns true if any of the notebook container fields is set.
+func hasContainerFields(spec *NotebookSpec) bool {
+	return spec.Image != "" || spec.Command != nil || spec.Args != nil ||
+		spec.Resources.Limits != nil || spec.Resources.Requests != nil || spec.Env != nil
+}
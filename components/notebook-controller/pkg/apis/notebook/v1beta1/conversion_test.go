@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+// TestSampleRoundTrip converts the sample manifests to the other version and
+// back, and checks that nothing is lost.
+func TestSampleRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../../../../config/samples/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no sample manifests found")
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		if typeMeta.Kind != "Notebook" {
+			continue
+		}
+
+		switch typeMeta.APIVersion {
+		case v1alpha1.SchemeGroupVersion.String():
+			in := &v1alpha1.Notebook{}
+			if err := yaml.Unmarshal(data, in); err != nil {
+				t.Fatalf("%v: %v", f, err)
+			}
+			checkAlphaRoundTrip(t, f, in)
+		case SchemeGroupVersion.String():
+			in := &Notebook{}
+			if err := yaml.Unmarshal(data, in); err != nil {
+				t.Fatalf("%v: %v", f, err)
+			}
+			checkBetaRoundTrip(t, f, in)
+		default:
+			t.Errorf("%v: unknown API version %v", f, typeMeta.APIVersion)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	fsGroup := int64(100)
+	nextTransition := metav1.NewTime(time.Date(2019, 2, 4, 7, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name string
+		nb   *v1alpha1.Notebook
+	}{
+		{
+			name: "empty",
+			nb:   &v1alpha1.Notebook{},
+		},
+		{
+			name: "all fields",
+			nb: &v1alpha1.Notebook{
+				TypeMeta: metav1.TypeMeta{APIVersion: "kubeflow.org/v1alpha1", Kind: "Notebook"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nb",
+					Namespace: "kubeflow-user",
+					Annotations: map[string]string{
+						v1alpha1.CullingDisabledAnnotation: "true",
+						v1alpha1.IdleTimeAnnotation:        "2h0m0s",
+						"other":                            "value",
+					},
+				},
+				Spec: v1alpha1.NotebookSpec{
+					Template: v1alpha1.NotebookTemplateSpec{Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:    "nb",
+								Image:   "jupyter",
+								Command: []string{"start.sh"},
+								Args:    []string{"--debug"},
+								Env:     []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+								},
+								VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+							},
+							{Name: "sidecar", Image: "proxy"},
+						},
+						Volumes: []corev1.Volume{{
+							Name:         "data",
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						}},
+						SecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+					}},
+					Workspace: &v1alpha1.WorkspaceSpec{
+						Size:         resource.MustParse("10Gi"),
+						RetainPolicy: v1alpha1.WorkspaceDelete,
+					},
+					TemplateRef: &v1alpha1.TemplateReference{
+						Kind: v1alpha1.ClusterNotebookTemplateKind,
+						Name: "tensorflow",
+					},
+					Stopped:      true,
+					CloneFrom:    "before-experiment",
+					ExposedPorts: []string{"tensorboard"},
+					ServiceAccount: &v1alpha1.ServiceAccountSpec{
+						RoleTemplate: "notebook-editor",
+					},
+					Schedule: &v1alpha1.ScheduleSpec{
+						Start:    "0 8 * * MON-FRI",
+						Stop:     "0 20 * * MON-FRI",
+						TimeZone: "Europe/Berlin",
+					},
+					DisableDefaultProbes: true,
+				},
+				Status: v1alpha1.NotebookStatus{
+					Conditions: []v1alpha1.NotebookCondition{{
+						Type:   v1alpha1.NotebookReady,
+						Status: corev1.ConditionTrue,
+						Reason: "Running",
+					}},
+					ReadyReplicas: 1,
+					URLs: []v1alpha1.NotebookURL{
+						{Name: "notebook", URL: "/notebook/test/nb/"},
+						{Name: "tensorboard", URL: "/notebook/test/nb/ports/tensorboard/"},
+					},
+					Schedule: &v1alpha1.ScheduleStatus{
+						Running:        true,
+						NextTransition: &nextTransition,
+					},
+				},
+			},
+		},
+		{
+			name: "culling annotations that are not canonical",
+			nb: &v1alpha1.Notebook{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						v1alpha1.CullingDisabledAnnotation: "false",
+						v1alpha1.IdleTimeAnnotation:        "2h",
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		checkAlphaRoundTrip(t, test.name, test.nb)
+	}
+}
+
+func TestConvertCulling(t *testing.T) {
+	in := &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				v1alpha1.CullingDisabledAnnotation: "true",
+				v1alpha1.IdleTimeAnnotation:        "2h0m0s",
+			},
+		},
+	}
+	out := &Notebook{}
+	if err := Convert_v1alpha1_Notebook_To_v1beta1_Notebook(in, out); err != nil {
+		t.Fatal(err)
+	}
+	want := &CullingPolicy{Disabled: true, IdleTime: &metav1.Duration{Duration: 2 * time.Hour}}
+	if !equality.Semantic.DeepEqual(out.Spec.Culling, want) {
+		t.Errorf("culling = %+v; want %+v", out.Spec.Culling, want)
+	}
+	if out.Annotations != nil {
+		t.Errorf("annotations = %v; want the culling annotations to be removed", out.Annotations)
+	}
+}
+
+func TestConvertWithoutContainers(t *testing.T) {
+	in := &Notebook{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb"},
+		Spec:       NotebookSpec{Image: "jupyter"},
+	}
+	out := &v1alpha1.Notebook{}
+	if err := Convert_v1beta1_Notebook_To_v1alpha1_Notebook(in, out); err != nil {
+		t.Fatal(err)
+	}
+	containers := out.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Name != "nb" || containers[0].Image != "jupyter" {
+		t.Errorf("containers = %+v; want a single container nb with image jupyter", containers)
+	}
+}
+
+func checkAlphaRoundTrip(t *testing.T, name string, in *v1alpha1.Notebook) {
+	beta := &Notebook{}
+	if err := Convert_v1alpha1_Notebook_To_v1beta1_Notebook(in, beta); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	out := &v1alpha1.Notebook{}
+	if err := Convert_v1beta1_Notebook_To_v1alpha1_Notebook(beta, out); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	want := in.DeepCopy()
+	if want.APIVersion == "" {
+		want.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	}
+	if !equality.Semantic.DeepEqual(out, want) {
+		t.Errorf("%v: round trip through v1beta1 changed the notebook: %v", name, diff.ObjectReflectDiff(want, out))
+	}
+}
+
+func checkBetaRoundTrip(t *testing.T, name string, in *Notebook) {
+	alpha := &v1alpha1.Notebook{}
+	if err := Convert_v1beta1_Notebook_To_v1alpha1_Notebook(in, alpha); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	out := &Notebook{}
+	if err := Convert_v1alpha1_Notebook_To_v1beta1_Notebook(alpha, out); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	if !equality.Semantic.DeepEqual(out, in) {
+		t.Errorf("%v: round trip through v1alpha1 changed the notebook: %v", name, diff.ObjectReflectDiff(in, out))
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleanup runs the hooks that clean up what a notebook leaves outside
+// the cluster, e.g. DNS entries, storage buckets or audit records, before the
+// controller releases its finalizer.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+)
+
+// Finalizer is the finalizer the controller adds to notebooks while cleanup hooks are configured.
+const Finalizer = "notebooks.kubeflow.org/cleanup"
+
+// Hook cleans up after a deleted notebook. A chain is run again from its first
+// hook when one of them fails, so hooks must be idempotent.
+type Hook interface {
+	// Name identifies the hook in the flags, events and status of notebooks.
+	Name() string
+	// Cleanup cleans up after the notebook, which is being deleted.
+	Cleanup(ctx context.Context, nb *v1alpha1.Notebook) error
+}
+
+// HookFunc adapts a function to a Hook.
+type HookFunc struct {
+	HookName string
+	Func     func(ctx context.Context, nb *v1alpha1.Notebook) error
+}
+
+// Name implements Hook.
+func (h HookFunc) Name() string { return h.HookName }
+
+// Cleanup implements Hook.
+func (h HookFunc) Cleanup(ctx context.Context, nb *v1alpha1.Notebook) error {
+	return h.Func(ctx, nb)
+}
+
+var (
+	mu      sync.Mutex
+	plugins = map[string]Hook{}
+)
+
+// Register makes an in-process hook available to NewChain under its name. It
+// is meant to be called from the init function of the package of the hook,
+// which is linked into the manager with a blank import.
+func Register(hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := plugins[hook.Name()]; ok {
+		panic(fmt.Sprintf("cleanup hook %v registered twice", hook.Name()))
+	}
+	plugins[hook.Name()] = hook
+}
+
+// Plugins returns the names of the registered in-process hooks.
+func Plugins() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain runs hooks in order.
+type Chain struct {
+	Hooks []Hook
+	// Timeout of each hook.
+	Timeout time.Duration
+}
+
+// NewChain returns the chain of the comma-separated hooks. Each hook is the name
+// of a registered in-process hook, or the http(s) URL of a webhook. It returns
+// nil if there are no hooks.
+func NewChain(hooks string, timeout time.Duration) (*Chain, error) {
+	c := &Chain{Timeout: timeout}
+	for _, name := range strings.Split(hooks, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+			u, err := url.Parse(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cleanup webhook: %v", err)
+			}
+			c.Hooks = append(c.Hooks, NewWebhook(u.String()))
+			continue
+		}
+		mu.Lock()
+		hook, ok := plugins[name]
+		mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown cleanup hook %q, registered hooks are %v", name, Plugins())
+		}
+		c.Hooks = append(c.Hooks, hook)
+	}
+	if len(c.Hooks) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// Error is the failure of a hook of a chain.
+type Error struct {
+	Hook string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cleanup hook %v failed: %v", e.Hook, e.Err)
+}
+
+// Run runs the hooks for the notebook in order, and stops at the first that
+// fails with an *Error.
+func (c *Chain) Run(ctx context.Context, nb *v1alpha1.Notebook) error {
+	for _, hook := range c.Hooks {
+		if err := c.runHook(ctx, hook, nb); err != nil {
+			return &Error{Hook: hook.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Chain) runHook(ctx context.Context, hook Hook, nb *v1alpha1.Notebook) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return hook.Cleanup(ctx, nb)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	hook := func(name string, err error) Hook {
+		return HookFunc{HookName: name, Func: func(ctx context.Context, nb *v1alpha1.Notebook) error {
+			calls = append(calls, name)
+			return err
+		}}
+	}
+	Register(hook("test-dns", nil))
+	Register(hook("test-bucket", fmt.Errorf("bucket is locked")))
+
+	c, err := NewChain("test-dns, test-bucket,", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Run(context.TODO(), &v1alpha1.Notebook{})
+	hookErr, ok := err.(*Error)
+	if !ok || hookErr.Hook != "test-bucket" {
+		t.Errorf("Run = %v; want the error of test-bucket", err)
+	}
+	if got := strings.Join(calls, ","); got != "test-dns,test-bucket" {
+		t.Errorf("hooks run = %v; want test-dns,test-bucket", got)
+	}
+
+	if _, err := NewChain("test-dns,missing", time.Second); err == nil {
+		t.Error("NewChain with an unknown hook succeeded; want an error")
+	}
+	if c, err := NewChain("", time.Second); c != nil || err != nil {
+		t.Errorf("NewChain without hooks = %v, %v; want nil, nil", c, err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got v1alpha1.Notebook
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %v %v; want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+		fmt.Fprintln(w, "dns entry is busy")
+	}))
+	defer server.Close()
+
+	c, err := NewChain(server.URL+"/hooks?token=secret", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := &v1alpha1.Notebook{ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"}}
+	if err := c.Run(context.TODO(), nb); err != nil {
+		t.Errorf("Run: %v", err)
+	}
+	if got.Namespace != "test" || got.Name != "nb" {
+		t.Errorf("webhook received notebook %v/%v; want test/nb", got.Namespace, got.Name)
+	}
+
+	status = http.StatusServiceUnavailable
+	err = c.Run(context.TODO(), nb)
+	want := "cleanup hook " + server.URL + "/hooks failed: 503 Service Unavailable: dns entry is busy"
+	if err == nil || err.Error() != want {
+		t.Errorf("Run = %v; want %v", err, want)
+	}
+}
+
+func TestWebhookConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	c, err := NewChain(server.URL+"/hooks?token=secret", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := &v1alpha1.Notebook{ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"}}
+	err = c.Run(context.TODO(), nb)
+	if err == nil {
+		t.Fatal("Run of a closed server succeeded")
+	}
+	// The query of the URL may hold secrets and is shown in events.
+	if msg := err.Error(); strings.Contains(msg, "secret") || !strings.Contains(msg, server.URL+"/hooks") {
+		t.Errorf("Run = %v; want the error of the POST to %v/hooks without the query", err, server.URL)
+	}
+}
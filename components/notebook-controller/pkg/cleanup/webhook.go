@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+)
+
+// Webhook is a hook that POSTs the deleted notebook as JSON to a URL. Any 2xx
+// response means the cleanup is done. Other responses and connection errors
+// are retried.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhook returns the webhook hook of the URL.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, Client: http.DefaultClient}
+}
+
+// Name implements Hook. Webhooks are named by their URL without credentials
+// or query, which may hold secrets.
+func (w *Webhook) Name() string {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return "webhook"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+// Cleanup implements Hook.
+func (w *Webhook) Cleanup(ctx context.Context, nb *v1alpha1.Notebook) error {
+	body, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return w.redact(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return w.redact(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: 256})
+		return fmt.Errorf("%v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// redact replaces the URL in the error of a request, which may hold secrets,
+// with the name of the webhook.
+func (w *Webhook) redact(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: w.Name(), Err: urlErr.Err}
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// workspaceDataSource returns the VolumeSnapshot the workspace of a notebook
+// cloned from a snapshot is restored from, or nil if there is none.
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooksnapshots,verbs=get;list;watch
+func (r *ReconcileNotebook) workspaceDataSource(instance *v1alpha1.Notebook) (*corev1.TypedLocalObjectReference, error) {
+	if instance.Spec.CloneFrom == "" {
+		return nil, nil
+	}
+	snapshot, err := podspec.ReadySnapshot(context.TODO(), r.Client, instance)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Status.VolumeSnapshotName == "" {
+		// The captured notebook had no workspace.
+		return nil, nil
+	}
+	group := util.VolumeSnapshotGroupVersionKind.Group
+	return &corev1.TypedLocalObjectReference{
+		APIGroup: &group,
+		Kind:     util.VolumeSnapshotGroupVersionKind.Kind,
+		Name:     snapshot.Status.VolumeSnapshotName,
+	}, nil
+}
+
+// notebooksForSnapshot maps a snapshot to the notebooks cloned from it, so they
+// are created when it is ready.
+func notebooksForSnapshot(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		notebooks := &v1alpha1.NotebookList{}
+		if err := c.List(context.TODO(), client.InNamespace(a.Meta.GetNamespace()), notebooks); err != nil {
+			log.Error(err, "unable to list the notebooks of a snapshot", "namespace", a.Meta.GetNamespace(), "name", a.Meta.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, nb := range notebooks.Items {
+			if nb.Spec.CloneFrom != a.Meta.GetName() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: nb.Name, Namespace: nb.Namespace},
+			})
+		}
+		return requests
+	}
+}
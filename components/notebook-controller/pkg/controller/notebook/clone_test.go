@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileClone(t *testing.T) {
+	snapshot := &v1alpha1.NotebookSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "before-experiment", Namespace: testKey.Namespace},
+		Spec:       v1alpha1.NotebookSnapshotSpec{NotebookName: "original"},
+	}
+	nb := newTestNotebook()
+	nb.Spec.CloneFrom = snapshot.Name
+	nb.Spec.Template.Spec.Containers[0].Image = ""
+	nb.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "USER", Value: "bob"}}
+	nb.Spec.Workspace = &v1alpha1.WorkspaceSpec{Size: resource.MustParse("10Gi"), StorageClassName: "standard"}
+	r := newTestReconciler(fake.NewFakeClientWithScheme(scheme.Scheme, nb, snapshot))
+
+	// Nothing is created until the snapshot is ready.
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := r.Get(context.TODO(), testKey, &appsv1.StatefulSet{}); !errors.IsNotFound(err) {
+		t.Fatalf("StatefulSet of a clone of a snapshot that isn't ready: %v; want NotFound", err)
+	}
+
+	snapshot.Status = v1alpha1.NotebookSnapshotStatus{
+		NotebookSpec: &v1alpha1.NotebookSpec{
+			Template: v1alpha1.NotebookTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "nb", Image: "jupyter:experiment"}},
+			}},
+		},
+		VolumeSnapshotName: snapshot.Name,
+		ReadyToUse:         true,
+	}
+	if err := r.Status().Update(context.TODO(), snapshot); err != nil {
+		t.Fatal(err)
+	}
+	_, ss := reconcileNotebook(t, r)
+	c := ss.Spec.Template.Spec.Containers[0]
+	if c.Image != "jupyter:experiment" || len(c.Env) != 1 || c.WorkingDir != v1alpha1.DefaultWorkingDir {
+		t.Errorf("notebook container = %+v; want the image of the snapshot, the env of the clone and the defaults", c)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	key := types.NamespacedName{Name: util.WorkspaceClaimName(testKey.Name), Namespace: testKey.Namespace}
+	if err := r.Get(context.TODO(), key, pvc); err != nil {
+		t.Fatal(err)
+	}
+	source := pvc.Spec.DataSource
+	if source == nil || source.Kind != "VolumeSnapshot" || source.Name != snapshot.Name ||
+		source.APIGroup == nil || *source.APIGroup != "snapshot.storage.k8s.io" {
+		t.Errorf("data source of the workspace = %+v; want the VolumeSnapshot %v", source, snapshot.Name)
+	}
+}
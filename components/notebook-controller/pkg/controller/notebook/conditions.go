@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCondition returns the condition of the given type, or nil if it is not set.
+func getCondition(status *v1alpha1.NotebookStatus, t v1alpha1.NotebookConditionType) *v1alpha1.NotebookCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == t {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// isConditionTrue returns true if the condition of the given type has status True.
+func isConditionTrue(status *v1alpha1.NotebookStatus, t v1alpha1.NotebookConditionType) bool {
+	c := getCondition(status, t)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+// setCondition adds or replaces the condition of the same type. The probe time
+// is only moved when the condition changes, and the transition time only when its
+// status changes, so that unchanged conditions don't cause status updates.
+// It returns true if anything changed.
+func setCondition(status *v1alpha1.NotebookStatus, cond v1alpha1.NotebookCondition) bool {
+	now := metav1.Now()
+	existing := getCondition(status, cond.Type)
+	if existing == nil {
+		cond.LastProbeTime = now
+		cond.LastTransitionTime = now
+		status.Conditions = append(status.Conditions, cond)
+		return true
+	}
+	if existing.Status == cond.Status && existing.Reason == cond.Reason && existing.Message == cond.Message {
+		return false
+	}
+	if existing.Status != cond.Status {
+		existing.LastTransitionTime = now
+	}
+	existing.LastProbeTime = now
+	existing.Status = cond.Status
+	existing.Reason = cond.Reason
+	existing.Message = cond.Message
+	return true
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"fmt"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/culler"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReconcileCulling records the activity of the notebook and sets its Culled
+// condition. ReconcileStatefulSet scales culled notebooks to zero. The status
+// itself is written by Reconcile.
+func (r *ReconcileNotebook) ReconcileCulling(instance *v1alpha1.Notebook) error {
+	if r.culler == nil {
+		return nil
+	}
+
+	// A stopped notebook has no server and is not culled. Resuming it, also by
+	// its schedule, counts as activity, so that it isn't culled again right away.
+	if isStopped(instance) {
+		return nil
+	}
+	if isConditionTrue(&instance.Status, v1alpha1.NotebookStopped) {
+		r.culler.Touch(instance)
+		if err := r.updateNotebook(instance); err != nil {
+			return err
+		}
+	}
+
+	// Only a running notebook has a server to ask. A culled notebook is woken up
+	// by touching its last activity annotation.
+	if !isConditionTrue(&instance.Status, v1alpha1.NotebookCulled) {
+		changed, err := r.culler.UpdateActivity(instance)
+		if err != nil {
+			log.Info("Unable to get notebook activity", "namespace", instance.Namespace, "name", instance.Name, "error", err.Error())
+		} else if changed {
+			if err := r.updateNotebook(instance); err != nil {
+				return err
+			}
+		}
+	}
+
+	cond := v1alpha1.NotebookCondition{
+		Type:   v1alpha1.NotebookCulled,
+		Status: corev1.ConditionFalse,
+		Reason: "Active",
+	}
+	if r.culler.IsIdle(instance) {
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = "Idle"
+		cond.Message = fmt.Sprintf("No activity since %v", culler.LastActivity(instance).UTC().Format(time.RFC3339))
+	}
+	if setCondition(&instance.Status, cond) && cond.Status == corev1.ConditionTrue {
+		r.recorder.Event(instance, corev1.EventTypeNormal, reasonCulled, cond.Message)
+	}
+	return nil
+}
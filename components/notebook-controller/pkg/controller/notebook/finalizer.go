@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/cleanup"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The reasons of the events recorded when the cleanup hooks of a deleted
+// notebook fail, succeed, or are no longer configured.
+const (
+	reasonCleanupFailed  = "CleanupFailed"
+	reasonCleanedUp      = "CleanedUp"
+	reasonCleanupSkipped = "CleanupSkipped"
+)
+
+// ReconcileFinalizer adds the cleanup finalizer to notebooks while cleanup hooks
+// are configured, and removes it otherwise. When a notebook with the finalizer
+// is deleted, it runs the cleanup hooks and then releases the notebook. A hook
+// that fails is reported in the CleanupFailed condition and an event, and the
+// hooks are run again with backoff. A deleted notebook whose finalizer outlived
+// the hooks is released without cleanup and a warning event. It returns true if the notebook is being
+// deleted, so there is nothing else to reconcile.
+func (r *ReconcileNotebook) ReconcileFinalizer(instance *v1alpha1.Notebook) (bool, error) {
+	has := hasFinalizer(instance, cleanup.Finalizer)
+	if instance.DeletionTimestamp != nil {
+		if !has {
+			return true, nil
+		}
+		if r.cleanup != nil {
+			if err := r.cleanup.Run(context.TODO(), instance); err != nil {
+				r.recorder.Event(instance, corev1.EventTypeWarning, reasonCleanupFailed, err.Error())
+				cond := v1alpha1.NotebookCondition{
+					Type:    v1alpha1.NotebookCleanupFailed,
+					Status:  corev1.ConditionTrue,
+					Reason:  "HookFailed",
+					Message: err.Error(),
+				}
+				if setCondition(&instance.Status, cond) {
+					if updateErr := r.Status().Update(context.TODO(), instance); updateErr != nil {
+						log.Error(updateErr, "unable to report the failed cleanup", "namespace", instance.Namespace, "name", instance.Name)
+					}
+				}
+				return true, err
+			}
+			r.recorder.Event(instance, corev1.EventTypeNormal, reasonCleanedUp, "Ran the cleanup hooks")
+		} else {
+			r.recorder.Event(instance, corev1.EventTypeWarning, reasonCleanupSkipped, "Released without cleanup, no cleanup hooks are configured")
+		}
+		log.Info("Removing the cleanup finalizer", "namespace", instance.Namespace, "name", instance.Name)
+		removeFinalizer(instance, cleanup.Finalizer)
+		return true, r.Update(context.TODO(), instance)
+	}
+
+	switch {
+	case r.cleanup != nil && !has:
+		log.Info("Adding the cleanup finalizer", "namespace", instance.Namespace, "name", instance.Name)
+		instance.Finalizers = append(instance.Finalizers, cleanup.Finalizer)
+	case r.cleanup == nil && has:
+		// The hooks are no longer configured.
+		log.Info("Removing the cleanup finalizer", "namespace", instance.Namespace, "name", instance.Name)
+		removeFinalizer(instance, cleanup.Finalizer)
+	default:
+		return false, nil
+	}
+	return false, r.Update(context.TODO(), instance)
+}
+
+func hasFinalizer(instance *v1alpha1.Notebook, finalizer string) bool {
+	for _, f := range instance.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(instance *v1alpha1.Notebook, finalizer string) {
+	var finalizers []string
+	for _, f := range instance.Finalizers {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	instance.Finalizers = finalizers
+}
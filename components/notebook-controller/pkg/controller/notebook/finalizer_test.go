@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/cleanup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileFinalizer(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(100)
+	r.recorder = recorder
+	hookErr := fmt.Errorf("bucket is locked")
+	var cleaned []string
+	r.cleanup = &cleanup.Chain{Hooks: []cleanup.Hook{cleanup.HookFunc{
+		HookName: "bucket",
+		Func: func(ctx context.Context, nb *v1alpha1.Notebook) error {
+			if hookErr != nil {
+				return hookErr
+			}
+			cleaned = append(cleaned, nb.Name)
+			return nil
+		},
+	}}, Timeout: time.Second}
+
+	nb, _ := reconcileNotebook(t, r)
+	if !hasFinalizer(nb, cleanup.Finalizer) {
+		t.Fatalf("finalizers = %v; want %v", nb.Finalizers, cleanup.Finalizer)
+	}
+
+	// The fake client deletes objects right away, so mark the notebook as deleted.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+		now := metav1.Now()
+		nb.DeletionTimestamp = &now
+	})
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err == nil {
+		t.Error("Reconcile with a failing hook succeeded; want an error to retry")
+	}
+	nb = &v1alpha1.Notebook{}
+	if err := c.Get(context.TODO(), testKey, nb); err != nil {
+		t.Fatal(err)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookCleanupFailed, corev1.ConditionTrue, "HookFailed")
+	if !hasFinalizer(nb, cleanup.Finalizer) {
+		t.Errorf("finalizers after a failed hook = %v; want %v", nb.Finalizers, cleanup.Finalizer)
+	}
+	if want := "Warning CleanupFailed cleanup hook bucket failed: bucket is locked"; !hasEvent(recorder, want) {
+		t.Errorf("missing event %q", want)
+	}
+
+	// The notebook is released once the hooks succeed.
+	hookErr = nil
+	nb, _ = reconcileNotebook(t, r)
+	if hasFinalizer(nb, cleanup.Finalizer) || len(cleaned) != 1 {
+		t.Errorf("finalizers = %v, cleaned up %v; want the notebook cleaned up and released", nb.Finalizers, cleaned)
+	}
+}
+
+func TestReconcileFinalizerWithoutHooks(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Finalizers = []string{"other", cleanup.Finalizer}
+	r := newTestReconciler(fake.NewFakeClient(nb))
+
+	nb, _ = reconcileNotebook(t, r)
+	if len(nb.Finalizers) != 1 || nb.Finalizers[0] != "other" {
+		t.Errorf("finalizers without hooks = %v; want [other]", nb.Finalizers)
+	}
+}
+
+func TestReconcileFinalizerDeletedWithoutHooks(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Finalizers = []string{cleanup.Finalizer}
+	now := metav1.Now()
+	nb.DeletionTimestamp = &now
+	c := fake.NewFakeClient(nb)
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(100)
+	r.recorder = recorder
+
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	nb = &v1alpha1.Notebook{}
+	if err := c.Get(context.TODO(), testKey, nb); err != nil {
+		t.Fatal(err)
+	}
+	if len(nb.Finalizers) != 0 {
+		t.Errorf("finalizers = %v; want the notebook released", nb.Finalizers)
+	}
+	if want := "Warning CleanupSkipped Released without cleanup, no cleanup hooks are configured"; !hasEvent(recorder, want) {
+		t.Errorf("missing event %q", want)
+	}
+}
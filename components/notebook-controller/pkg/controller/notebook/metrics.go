@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	notebookCreations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notebook_create_total",
+		Help: "Number of notebooks whose StatefulSet was created",
+	}, []string{"namespace"})
+	notebookCullings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notebook_culling_total",
+		Help: "Number of times notebooks were culled",
+	}, []string{"namespace"})
+	notebookFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notebook_fail_total",
+		Help: "Number of times the notebook container failed to start or run",
+	}, []string{"namespace"})
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notebook_reconcile_errors_total",
+		Help: "Number of failed reconciles of notebooks",
+	}, []string{"namespace"})
+
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "notebook_reconcile_duration_seconds",
+		Help:    "Duration of the reconciliation of a notebook",
+		Buckets: prometheus.DefBuckets,
+	})
+	firstReadyDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "notebook_first_ready_seconds",
+		Help:    "Time from the creation of a notebook to its Pod being ready for the first time",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	runningDesc = prometheus.NewDesc("notebook_running",
+		"Number of notebooks with a ready Pod", []string{"namespace"}, nil)
+	culledDesc = prometheus.NewDesc("notebook_culled",
+		"Number of culled notebooks", []string{"namespace"}, nil)
+	failedDesc = prometheus.NewDesc("notebook_failed",
+		"Number of notebooks whose container failed to start or run", []string{"namespace"}, nil)
+)
+
+func init() {
+	// Register the metrics with the registry served by the manager on --metrics-addr.
+	metrics.Registry.MustRegister(
+		notebookCreations,
+		notebookCullings,
+		notebookFailures,
+		reconcileErrors,
+		reconcileDuration,
+		firstReadyDuration,
+	)
+}
+
+// isFailed returns true if the notebook container is crashing or cannot be started,
+// e.g. because of ImagePullBackOff or CrashLoopBackOff.
+func isFailed(status *v1alpha1.NotebookStatus) bool {
+	state := status.ContainerState
+	if state.Terminated != nil {
+		return true
+	}
+	if state.Waiting == nil {
+		return false
+	}
+	switch state.Waiting.Reason {
+	case "", "ContainerCreating", "PodInitializing":
+		return false
+	}
+	return true
+}
+
+// recordTransitions updates the counters for the changes of the notebook status
+// made by a reconcile, and observes the time it took a new notebook to be ready.
+func (r *ReconcileNotebook) recordTransitions(instance *v1alpha1.Notebook, old *v1alpha1.NotebookStatus) {
+	ns := instance.Namespace
+	if isConditionTrue(&instance.Status, v1alpha1.NotebookCulled) && !isConditionTrue(old, v1alpha1.NotebookCulled) {
+		notebookCullings.WithLabelValues(ns).Inc()
+	}
+	if isFailed(&instance.Status) && !isFailed(old) {
+		notebookFailures.WithLabelValues(ns).Inc()
+	}
+
+	// Only notebooks created while the controller runs are observed, as the
+	// controller can't tell if older notebooks have been ready before.
+	if !isConditionTrue(&instance.Status, v1alpha1.NotebookReady) || isConditionTrue(old, v1alpha1.NotebookReady) ||
+		instance.CreationTimestamp.Time.Before(r.startTime) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := client.ObjectKey{Namespace: instance.Namespace, Name: instance.Name}
+	if uid, ok := r.readyNotebooks[key]; ok && uid == instance.UID {
+		return
+	}
+	r.readyNotebooks[key] = instance.UID
+	firstReadyDuration.Observe(time.Since(instance.CreationTimestamp.Time).Seconds())
+}
+
+// forgetNotebook drops the state kept for a deleted notebook.
+func (r *ReconcileNotebook) forgetNotebook(key client.ObjectKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.readyNotebooks, key)
+}
+
+// notebookCollector reports the number of running, culled and failed notebooks
+// per namespace. The notebooks are read from the caches of the managers the
+// controller is added to, one per watched namespace, when the metrics are scraped.
+type notebookCollector struct {
+	mu      sync.Mutex
+	clients []client.Client
+}
+
+// collector is registered once, when the controller is added to the first manager.
+var collector = &notebookCollector{}
+
+// addClient adds the client of a manager, and returns true for the first one.
+func (c *notebookCollector) addClient(cl client.Client) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients = append(c.clients, cl)
+	return len(c.clients) == 1
+}
+
+var _ prometheus.Collector = &notebookCollector{}
+
+func (c *notebookCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- runningDesc
+	ch <- culledDesc
+	ch <- failedDesc
+}
+
+func (c *notebookCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	clients := c.clients
+	c.mu.Unlock()
+
+	running := map[string]float64{}
+	culled := map[string]float64{}
+	failed := map[string]float64{}
+	for _, cl := range clients {
+		notebooks := &v1alpha1.NotebookList{}
+		if err := cl.List(context.TODO(), &client.ListOptions{}, notebooks); err != nil {
+			log.Error(err, "unable to list notebooks for metrics")
+			return
+		}
+		for i := range notebooks.Items {
+			nb := &notebooks.Items[i]
+			if nb.Status.ReadyReplicas > 0 {
+				running[nb.Namespace]++
+			}
+			if isConditionTrue(&nb.Status, v1alpha1.NotebookCulled) {
+				culled[nb.Namespace]++
+			}
+			if isFailed(&nb.Status) {
+				failed[nb.Namespace]++
+			}
+		}
+	}
+	for ns, v := range running {
+		ch <- prometheus.MustNewConstMetric(runningDesc, prometheus.GaugeValue, v, ns)
+	}
+	for ns, v := range culled {
+		ch <- prometheus.MustNewConstMetric(culledDesc, prometheus.GaugeValue, v, ns)
+	}
+	for ns, v := range failed {
+		ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.GaugeValue, v, ns)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func firstReadyCount(t *testing.T) uint64 {
+	m := &dto.Metric{}
+	if err := firstReadyDuration.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetrics(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+	created := testutil.ToFloat64(notebookCreations.WithLabelValues("test"))
+	failed := testutil.ToFloat64(notebookFailures.WithLabelValues("test"))
+	ready := firstReadyCount(t)
+
+	reconcileNotebook(t, r)
+	if got := testutil.ToFloat64(notebookCreations.WithLabelValues("test")); got != created+1 {
+		t.Errorf("notebook_create_total = %v; want %v", got, created+1)
+	}
+
+	// The image can't be pulled.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb-0", Namespace: "test"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "nb",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+				},
+			}},
+		},
+	}
+	if err := c.Create(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNotebook(t, r)
+	reconcileNotebook(t, r)
+	if got := testutil.ToFloat64(notebookFailures.WithLabelValues("test")); got != failed+1 {
+		t.Errorf("notebook_fail_total = %v; want %v", got, failed+1)
+	}
+	collector := &notebookCollector{clients: []client.Client{c}}
+	want := `
+# HELP notebook_failed Number of notebooks whose container failed to start or run
+# TYPE notebook_failed gauge
+notebook_failed{namespace="test"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "notebook_failed"); err != nil {
+		t.Error(err)
+	}
+
+	// The Pod becomes ready.
+	pod.Status = corev1.PodStatus{
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "nb",
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}},
+	}
+	if err := c.Update(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNotebook(t, r)
+	if got := firstReadyCount(t); got != ready+1 {
+		t.Errorf("notebook_first_ready_seconds count = %v; want %v", got, ready+1)
+	}
+	want = `
+# HELP notebook_failed Number of notebooks whose container failed to start or run
+# TYPE notebook_failed gauge
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "notebook_failed"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReconcileErrors(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Annotations = map[string]string{routing.ProviderAnnotation: "nginx"}
+	r := newTestReconciler(fake.NewFakeClient(nb))
+	recorder := record.NewFakeRecorder(100)
+	r.recorder = recorder
+	before := testutil.ToFloat64(reconcileErrors.WithLabelValues("test"))
+
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err == nil {
+		t.Fatal("Reconcile with an unknown routing provider succeeded")
+	}
+	if got := testutil.ToFloat64(reconcileErrors.WithLabelValues("test")); got != before+1 {
+		t.Errorf("notebook_reconcile_errors_total = %v; want %v", got, before+1)
+	}
+	found := false
+	for len(recorder.Events) > 0 {
+		if strings.HasPrefix(<-recorder.Events, "Warning ReconcileFailed ") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing ReconcileFailed event")
+	}
+}
@@ -19,23 +19,33 @@ package notebook
 import (
 	"context"
 	"reflect"
-	"strconv"
-	"strings"
+	"sync"
+	"time"
 
 	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/cleanup"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/culler"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/health"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/shutdown"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/sidecar"
 	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -43,27 +53,70 @@ import (
 
 var log = logf.Log.WithName("controller")
 
-const DefaultContainerPort = 8888
-
-// The default fsGroup of PodSecurityContext.
-// https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.11/#podsecuritycontext-v1-core
-const DefaultFSGroup = int64(100)
+// The reasons of the events recorded on notebooks.
+const (
+	reasonCreated        = "Created"
+	reasonUpdated        = "Updated"
+	reasonDeleted        = "Deleted"
+	reasonCulled         = "Culled"
+	reasonInvalidSpec    = "InvalidSpec"
+	reasonPolicyViolated = "PolicyViolated"
+	reasonFailed         = "ReconcileFailed"
+)
 
 // Add creates a new Notebook Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	if collector.addClient(mgr.GetClient()) {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, r)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileNotebook{Client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
+	r := &ReconcileNotebook{
+		Client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetRecorder("notebook-controller"),
+		routing: routing.NewRegistry(DefaultOptions.RoutingProvider,
+			routing.Ambassador{},
+			&routing.Istio{Gateway: DefaultOptions.IstioGateway},
+			&routing.Ingress{Class: DefaultOptions.IngressClass},
+		),
+		defaultProbes:  DefaultOptions.DefaultProbes,
+		now:            time.Now,
+		startTime:      time.Now(),
+		readyNotebooks: map[types.NamespacedName]types.UID{},
+	}
+	if DefaultOptions.EnableCulling {
+		r.culler = culler.New(culler.NewJupyterActivitySource(), DefaultOptions.IdleTime, DefaultOptions.CullingCheckPeriod)
+	}
+	chain, err := cleanup.NewChain(DefaultOptions.CleanupHooks, DefaultOptions.CleanupHookTimeout)
+	if err != nil {
+		return nil, err
+	}
+	r.cleanup = chain
+	if DefaultOptions.SidecarConfigMap != "" {
+		injector, err := sidecar.NewInjector(mgr.GetClient(), DefaultOptions.SidecarConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		r.sidecars = injector
+	}
+	return r, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("notebook-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("notebook-controller", mgr, controller.Options{Reconciler: shutdown.Track(health.Track(r))})
 	if err != nil {
 		return err
 	}
@@ -74,7 +127,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	err = c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+	err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &v1alpha1.Notebook{},
 	})
@@ -90,6 +143,101 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	if err := watchRoutes(c, mgr); err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(notebookForWorkspaceClaim),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The ServiceAccount of a notebook, and its Role and RoleBinding.
+	for _, obj := range []runtime.Object{&corev1.ServiceAccount{}, &rbacv1.Role{}, &rbacv1.RoleBinding{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &v1alpha1.Notebook{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The Roles of notebooks follow the rules of their role template.
+	err = c.Watch(&source.Kind{Type: &rbacv1.ClusterRole{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: notebooksForRoleTemplate(mgr.GetClient()),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notebooks created from a template are reconciled when it changes.
+	err = c.Watch(&source.Kind{Type: &v1alpha1.NotebookTemplate{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: notebooksForTemplate(mgr.GetClient(), v1alpha1.NotebookTemplateKind),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &v1alpha1.ClusterNotebookTemplate{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: notebooksForTemplate(mgr.GetClient(), v1alpha1.ClusterNotebookTemplateKind),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notebooks cloned from a snapshot are created when it is ready.
+	err = c.Watch(&source.Kind{Type: &v1alpha1.NotebookSnapshot{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: notebooksForSnapshot(mgr.GetClient()),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The notebooks of a namespace are checked again when its policies change.
+	err = c.Watch(&source.Kind{Type: &v1alpha1.NotebookPolicy{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: notebooksForPolicy(mgr.GetClient()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if DefaultOptions.SidecarConfigMap != "" {
+		// The sidecars of notebooks change with their definitions and with the
+		// labels of the namespaces.
+		err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: notebooksForSidecarConfig(mgr.GetClient(), DefaultOptions.SidecarConfigMap),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: notebooksForNamespace(mgr.GetClient()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pods are owned by the StatefulSet, so map them to the Notebook through their label.
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			name, ok := a.Meta.GetLabels()["statefulset"]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: name, Namespace: a.Meta.GetNamespace()}},
+			}
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -99,6 +247,28 @@ var _ reconcile.Reconciler = &ReconcileNotebook{}
 type ReconcileNotebook struct {
 	client.Client
 	scheme *runtime.Scheme
+	// recorder emits the events of the notebooks.
+	recorder record.EventRecorder
+	// culler scales idle notebooks down. Culling is disabled when it is nil.
+	culler *culler.Culler
+	// routing selects how external traffic reaches each notebook.
+	routing *routing.Registry
+	// sidecars injects sidecars into the notebook Pods. Injection is disabled when it is nil.
+	sidecars *sidecar.Injector
+	// cleanup runs the cleanup hooks of deleted notebooks. Notebooks get no
+	// finalizer when it is nil.
+	cleanup *cleanup.Chain
+	// defaultProbes adds probes of the Jupyter API to notebook containers without probes.
+	defaultProbes bool
+	// now returns the current time for the schedules of notebooks. It is
+	// replaced in tests.
+	now func() time.Time
+
+	// startTime and readyNotebooks track which notebooks created since the
+	// controller started have been ready, for the notebook_first_ready_seconds metric.
+	startTime      time.Time
+	mu             sync.Mutex
+	readyNotebooks map[types.NamespacedName]types.UID
 }
 
 // Reconcile reads that state of the cluster for a Notebook object and makes changes based on the state read
@@ -108,9 +278,14 @@ type ReconcileNotebook struct {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=kubeflow.org,resources=notebooks,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kubeflow.org,resources=notebooks/status,verbs=get;update;patch
 func (r *ReconcileNotebook) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	defer func(start time.Time) {
+		reconcileDuration.Observe(time.Since(start).Seconds())
+	}(time.Now())
+
 	// Fetch the Notebook instance
 	instance := &v1alpha1.Notebook{}
 	err := r.Get(context.TODO(), request.NamespacedName, instance)
@@ -118,59 +293,142 @@ func (r *ReconcileNotebook) Reconcile(request reconcile.Request) (reconcile.Resu
 		if errors.IsNotFound(err) {
 			// Object not found, return.  Created objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
+			r.forgetNotebook(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
-	if err = r.ReconcileStatefulSet(instance); err != nil {
-		return reconcile.Result{}, err
+	if deleting, err := r.ReconcileFinalizer(instance); deleting || err != nil {
+		if err != nil {
+			return r.reconcileFailed(instance, err)
+		}
+		return reconcile.Result{}, nil
 	}
-	if err = r.ReconcileService(instance); err != nil {
-		return reconcile.Result{}, err
+	status := instance.Status.DeepCopy()
+	podSpec, err := r.notebookPodSpec(instance)
+	if podspec.IsPending(err) {
+		// The notebook is reconciled again when its template is created, or its
+		// snapshot is ready.
+		log.Info("Template or snapshot of the notebook not ready", "namespace", instance.Namespace, "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, reasonInvalidSpec, err.Error())
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	if len(podSpec.Containers) == 0 {
+		// Rejected by the validating webhook; nothing to run until the spec is fixed.
+		log.Info("Notebook has no containers", "namespace", instance.Namespace, "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, reasonInvalidSpec, "The notebook has no containers")
+		return reconcile.Result{}, nil
+	}
+	untilTransition := r.ReconcileSchedule(instance)
+	if err = r.ReconcileCulling(instance); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	claimed, err := r.ReconcileWorkspace(instance)
+	if err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	if err = r.ReconcileServiceAccount(instance); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	if !claimed {
+		// The Pod would stay pending until its workspace claim exists.
+		log.Info("Workspace of the notebook not claimed", "namespace", instance.Namespace, "name", instance.Name)
+	} else if err = r.ReconcileStatefulSet(instance, podSpec); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	ports := r.notebookPorts(instance, podSpec)
+	if err = r.ReconcileService(instance, ports); err != nil {
+		return r.reconcileFailed(instance, err)
 	}
-	return reconcile.Result{}, nil
+	if err = r.ReconcileRoute(instance, ports); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	if err = r.ReconcilePolicy(instance, podSpec); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	if err = r.ReconcileStatus(instance, podSpec); err != nil {
+		return r.reconcileFailed(instance, err)
+	}
+	r.recordTransitions(instance, status)
+	if !reflect.DeepEqual(status, &instance.Status) {
+		log.Info("Updating Notebook status", "namespace", instance.Namespace, "name", instance.Name)
+		if err = r.Status().Update(context.TODO(), instance); err != nil {
+			return r.reconcileFailed(instance, err)
+		}
+	}
+	result := reconcile.Result{}
+	if r.culler != nil && !isStopped(instance) && !isConditionTrue(&instance.Status, v1alpha1.NotebookCulled) {
+		// Poll the activity of running notebooks.
+		result.RequeueAfter = r.culler.CheckPeriod
+	}
+	if untilTransition > 0 && (result.RequeueAfter == 0 || untilTransition < result.RequeueAfter) {
+		// Start or stop the notebook on time.
+		result.RequeueAfter = untilTransition
+	}
+	return result, nil
 }
 
-// ReconcileStatefulSet reconciles the StatefulSet object for the notebook.
-func (r *ReconcileNotebook) ReconcileStatefulSet(instance *v1alpha1.Notebook) error {
+// reconcileFailed records a warning event for err on the notebook, counts it in
+// notebook_reconcile_errors_total, and returns the error to requeue it.
+// Conflicts are retried without an event.
+func (r *ReconcileNotebook) reconcileFailed(instance *v1alpha1.Notebook, err error) (reconcile.Result, error) {
+	if !errors.IsConflict(err) {
+		r.recorder.Event(instance, corev1.EventTypeWarning, reasonFailed, err.Error())
+		reconcileErrors.WithLabelValues(instance.Namespace).Inc()
+	}
+	return reconcile.Result{}, err
+}
+
+// updateNotebook updates the metadata and spec of the notebook during a
+// reconcile. The API server ignores the status on Update and returns the
+// stored one, so the status computed so far is kept and written by Reconcile.
+func (r *ReconcileNotebook) updateNotebook(instance *v1alpha1.Notebook) error {
+	status := instance.Status.DeepCopy()
+	err := r.Update(context.TODO(), instance)
+	instance.Status = *status
+	return err
+}
+
+// ReconcileStatefulSet reconciles the StatefulSet object for the notebook, which
+// runs podSpec with the workspace, sidecars and default probes of the notebook added. Defaults
+// for the PodSpec are set by the mutating webhook.
+// Stopped and culled notebooks, and notebooks outside the windows of their
+// schedule, are scaled to zero. Their Pod template is still
+// updated, so a rollout of a new template happens when they are resumed.
+func (r *ReconcileNotebook) ReconcileStatefulSet(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) error {
 	// Define the desired StatefulSet object
+	replicas := int32(1)
+	if isStopped(instance) || isConditionTrue(&instance.Status, v1alpha1.NotebookCulled) {
+		replicas = 0
+	}
 	ss := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instance.Name,
 			Namespace: instance.Namespace,
 		},
 		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{"statefulset": instance.Name},
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"statefulset": instance.Name}},
-				Spec:       instance.Spec.Template.Spec,
+				Spec:       *podSpec.DeepCopy(),
 			},
 		},
 	}
-	podSpec := &ss.Spec.Template.Spec
-	container := &podSpec.Containers[0]
-	if container.WorkingDir == "" {
-		container.WorkingDir = "/home/jovyan"
-	}
-	if container.Ports == nil {
-		container.Ports = []corev1.ContainerPort{
-			corev1.ContainerPort{
-				ContainerPort: DefaultContainerPort,
-				Name:          "notebook-port",
-				Protocol:      "TCP",
-			},
-		}
+	addWorkspaceVolume(instance, &ss.Spec.Template.Spec)
+	addServiceAccount(instance, &ss.Spec.Template.Spec)
+	r.addDefaultProbes(instance, &ss.Spec.Template.Spec)
+	if err := r.injectSidecars(instance, &ss.Spec.Template.Spec); err != nil {
+		return err
 	}
-	if podSpec.SecurityContext == nil {
-		fsGroup := DefaultFSGroup
-		podSpec.SecurityContext = &corev1.PodSecurityContext{
-			FSGroup: &fsGroup,
-		}
+	ss.Annotations = map[string]string{
+		util.PodTemplateHashAnnotation: util.PodTemplateHash(&ss.Spec.Template.Spec),
 	}
-
 	if err := controllerutil.SetControllerReference(instance, ss, r.scheme); err != nil {
 		return err
 	}
@@ -184,74 +442,69 @@ func (r *ReconcileNotebook) ReconcileStatefulSet(instance *v1alpha1.Notebook) er
 		if err != nil {
 			return err
 		}
+		found = ss
+		notebookCreations.WithLabelValues(instance.Namespace).Inc()
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created StatefulSet %v", ss.Name)
 	} else if err != nil {
 		return err
 	}
 
-	// Update the found object and write the result back if there are any changes
-	if !reflect.DeepEqual(ss.Spec, found.Spec) {
-		found.Spec = ss.Spec
+	// Update the found object and write the result back if there are any changes.
+	// Only the fields set by the controller are compared, so the defaults filled
+	// in by the API server don't cause an update on every reconcile.
+	if util.CopyStatefulSetFields(ss, found) {
 		log.Info("Updating StatefulSet", "namespace", ss.Namespace, "name", ss.Name)
 		err = r.Update(context.TODO(), found)
 		if err != nil {
 			return err
 		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonUpdated, "Updated StatefulSet %v to match the notebook spec", ss.Name)
 	}
 	return nil
 }
 
-// ReconcileService reconciles the Service object for the notebook.
-func (r *ReconcileNotebook) ReconcileService(instance *v1alpha1.Notebook) error {
+// ReconcileService reconciles the Service object for the notebook, which
+// publishes the routed ports of the notebook.
+func (r *ReconcileNotebook) ReconcileService(instance *v1alpha1.Notebook, ports []routing.Port) error {
 	// Define the desired Service object
-	port := DefaultContainerPort
-	containerPorts := instance.Spec.Template.Spec.Containers[0].Ports
-	if containerPorts != nil {
-		port = int(containerPorts[0].ContainerPort)
+	provider, err := r.routing.ProviderFor(instance)
+	if err != nil {
+		return err
 	}
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      instance.Name,
-			Namespace: instance.Namespace,
-			Annotations: map[string]string{
-				"getambassador.io/config": strings.Join(
-					[]string{
-						"---",
-						"apiVersion: ambassador/v0",
-						"kind:  Mapping",
-						"name: notebook_" + instance.Namespace + "_" + instance.Name + "_mapping",
-						"prefix: /notebook/" + instance.Namespace + "/" + instance.Name,
-						"rewrite: /" + instance.Namespace + "/" + instance.Name,
-						"timeout_ms: 300000",
-						"service: " + instance.Name + "." + instance.Namespace + ":" + strconv.Itoa(port),
-						"use_websocket: true",
-					}, "\n"),
-			},
+			Name:        instance.Name,
+			Namespace:   instance.Namespace,
+			Annotations: provider.ServiceAnnotations(instance, ports),
 		},
 		Spec: corev1.ServiceSpec{
 			Type:     "ClusterIP",
 			Selector: map[string]string{"statefulset": instance.Name},
-			Ports: []corev1.ServicePort{
-				corev1.ServicePort{
-					Port:       80,
-					TargetPort: intstr.FromInt(port),
-					Protocol:   "TCP",
-				},
-			},
 		},
 	}
+	for _, p := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       p.ServicePortName(),
+			Port:       int32(p.ServicePort),
+			TargetPort: intstr.FromInt(p.TargetPort),
+			Protocol:   "TCP",
+		})
+	}
 	if err := controllerutil.SetControllerReference(instance, svc, r.scheme); err != nil {
 		return err
 	}
 
 	// Check if the Service already exists
 	found := &corev1.Service{}
-	err := r.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, found)
+	err = r.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
 		log.Info("Creating Service", "namespace", svc.Namespace, "name", svc.Name)
 		err = r.Create(context.TODO(), svc)
 		if err != nil {
 			return err
 		}
+		found = svc
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created Service %v", svc.Name)
 	} else if err != nil {
 		return err
 	}
@@ -263,6 +516,7 @@ func (r *ReconcileNotebook) ReconcileService(instance *v1alpha1.Notebook) error
 		if err != nil {
 			return err
 		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonUpdated, "Updated Service %v to match the notebook spec", svc.Name)
 	}
 	return nil
 }
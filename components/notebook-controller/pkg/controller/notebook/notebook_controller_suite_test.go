@@ -0,0 +1,457 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// The integration tests run the controller wired up by Add against a fake API
+// server: a fake client whose writes are delivered to the watches of the
+// controller, and which garbage collects the objects owned by deleted objects.
+// No cluster or control plane binaries are needed.
+
+// testClient is the client shared by the controller and the tests.
+var testClient client.Client
+
+func TestMain(m *testing.M) {
+	var err error
+	if crdSchemas, err = readCRDSchemas(filepath.Join("..", "..", "..", "config", "crds")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	mgr := newTestManager()
+	if err := Add(mgr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	testClient = mgr.GetClient()
+	stop := make(chan struct{})
+	go func() {
+		if err := mgr.Start(stop); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}()
+
+	code := m.Run()
+	close(stop)
+	os.Exit(code)
+}
+
+// crdSchemas are the OpenAPI schemas of the kinds defined by the CRDs.
+var crdSchemas map[schema.GroupVersionKind]map[string]interface{}
+
+// readCRDSchemas reads the OpenAPI schemas of the CRDs in dir by kind, and
+// checks that they define the kinds watched by the controller. The fake
+// client serves every kind of the scheme, so the CRDs are not installed, but
+// the fixtures of the tests are checked against their schemas.
+func readCRDSchemas(dir string) (map[schema.GroupVersionKind]map[string]interface{}, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	schemas := map[schema.GroupVersionKind]map[string]interface{}{}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		crd := struct {
+			Kind string `json:"kind"`
+			Spec struct {
+				Group    string `json:"group"`
+				Version  string `json:"version"`
+				Versions []struct {
+					Name string `json:"name"`
+				} `json:"versions"`
+				Names struct {
+					Kind string `json:"kind"`
+				} `json:"names"`
+				Validation struct {
+					OpenAPIV3Schema map[string]interface{} `json:"openAPIV3Schema"`
+				} `json:"validation"`
+			} `json:"spec"`
+		}{}
+		if err := yaml.Unmarshal(data, &crd); err != nil {
+			return nil, fmt.Errorf("%v: %v", f, err)
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		// All versions share the schema of the CRD.
+		versions := []string{crd.Spec.Version}
+		for _, v := range crd.Spec.Versions {
+			versions = append(versions, v.Name)
+		}
+		for _, v := range versions {
+			gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: v, Kind: crd.Spec.Names.Kind}
+			schemas[gvk] = crd.Spec.Validation.OpenAPIV3Schema
+		}
+	}
+
+	gvk := v1alpha1.SchemeGroupVersion.WithKind("Notebook")
+	if schemas[gvk] == nil {
+		return nil, fmt.Errorf("no CRD in %v defines the schema of %v", dir, gvk)
+	}
+	return schemas, nil
+}
+
+// validateSchema returns the fields of value, a JSON value at fldPath, that
+// don't match the OpenAPI schema: values of the wrong type, missing required
+// fields, and fields not declared by schemas that declare properties.
+func validateSchema(fldPath string, value interface{}, schema map[string]interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	var errs []string
+	typ := schema["type"]
+	if _, ok := schema["properties"]; ok && typ == nil {
+		// The root of the schemas generated by kubebuilder has no type.
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%v: got %T, want an object", fldPath, value)}
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%v.%v: required field missing", fldPath, name))
+			}
+		}
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return errs
+		}
+		for name, v := range obj {
+			prop, ok := properties[name].(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%v.%v: field not declared in the schema", fldPath, name))
+				continue
+			}
+			errs = append(errs, validateSchema(fldPath+"."+name, v, prop)...)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%v: got %T, want an array", fldPath, value)}
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			errs = append(errs, validateSchema(fmt.Sprintf("%v[%d]", fldPath, i), item, itemSchema)...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%v: got %T, want a string", fldPath, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%v: got %T, want a boolean", fldPath, value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			errs = append(errs, fmt.Sprintf("%v: got %v, want an integer", fldPath, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			errs = append(errs, fmt.Sprintf("%v: got %T, want a number", fldPath, value))
+		}
+	}
+	return errs
+}
+
+// checkSchema checks obj against the schema of the CRD of its kind.
+func checkSchema(t *testing.T, obj runtime.Object) {
+	t.Helper()
+	gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := crdSchemas[gvk]
+	if schema == nil {
+		t.Fatalf("no CRD defines the schema of %v", gvk)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range validateSchema(gvk.Kind, value, schema) {
+		t.Errorf("%v does not match the CRD: %v", gvk.Kind, err)
+	}
+}
+
+// testManager is a manager.Manager that runs controllers with a watchingClient
+// and the fake informers it feeds.
+type testManager struct {
+	client    client.Client
+	informers *informertest.FakeInformers
+	runnables []manager.Runnable
+	stop      chan struct{}
+}
+
+var _ manager.Manager = &testManager{}
+
+func newTestManager() *testManager {
+	informers := &informertest.FakeInformers{Scheme: scheme.Scheme}
+	return &testManager{
+		client: &watchingClient{
+			Client:    fake.NewFakeClient(),
+			informers: informers,
+		},
+		informers: informers,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (m *testManager) Add(r manager.Runnable) error {
+	if err := m.SetFields(r); err != nil {
+		return err
+	}
+	m.runnables = append(m.runnables, r)
+	return nil
+}
+
+func (m *testManager) SetFields(i interface{}) error {
+	if _, err := inject.ClientInto(m.client, i); err != nil {
+		return err
+	}
+	if _, err := inject.SchemeInto(scheme.Scheme, i); err != nil {
+		return err
+	}
+	if _, err := inject.CacheInto(m.informers, i); err != nil {
+		return err
+	}
+	if _, err := inject.InjectorInto(m.SetFields, i); err != nil {
+		return err
+	}
+	if _, err := inject.StopChannelInto(m.stop, i); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *testManager) Start(stop <-chan struct{}) error {
+	errCh := make(chan error, len(m.runnables))
+	for _, r := range m.runnables {
+		go func(r manager.Runnable) {
+			errCh <- r.Start(stop)
+		}(r)
+	}
+	select {
+	case <-stop:
+		close(m.stop)
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+func (m *testManager) GetConfig() *rest.Config                      { return &rest.Config{} }
+func (m *testManager) GetScheme() *runtime.Scheme                   { return scheme.Scheme }
+func (m *testManager) GetAdmissionDecoder() admissiontypes.Decoder  { return nil }
+func (m *testManager) GetClient() client.Client                     { return m.client }
+func (m *testManager) GetFieldIndexer() client.FieldIndexer         { return m.informers }
+func (m *testManager) GetCache() cache.Cache                        { return m.informers }
+func (m *testManager) GetRecorder(name string) record.EventRecorder { return &record.FakeRecorder{} }
+func (m *testManager) GetRESTMapper() meta.RESTMapper               { return testRESTMapper }
+
+// testRESTMapper knows the Ingresses but not the Istio VirtualServices, as in
+// a cluster without Istio.
+var testRESTMapper = func() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(extv1beta1.SchemeGroupVersion.WithKind("Ingress"), meta.RESTScopeNamespace)
+	return mapper
+}()
+
+// watchingClient is a client that sends an event to the fake informer of the
+// kind of every object it writes, like the watches of an API server. Like the
+// API server, it sets the UID of new objects, and like the garbage collector,
+// it deletes the objects owned by deleted objects.
+type watchingClient struct {
+	client.Client
+	// mu serializes the events, and the access to the informers of the kinds
+	// not watched by the controller, which are created on demand.
+	mu        sync.Mutex
+	informers *informertest.FakeInformers
+}
+
+var uidCounter int64
+
+func (c *watchingClient) Create(ctx context.Context, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if accessor.GetUID() == "" {
+		accessor.SetUID(types.UID(fmt.Sprintf("uid-%d", atomic.AddInt64(&uidCounter, 1))))
+	}
+	if ts := accessor.GetCreationTimestamp(); ts.IsZero() {
+		accessor.SetCreationTimestamp(metav1.Now())
+	}
+	if err := c.Client.Create(ctx, obj); err != nil {
+		return err
+	}
+	return c.notify(obj, func(i *controllertest.FakeInformer) {
+		i.Add(obj.DeepCopyObject().(metav1.Object))
+	})
+}
+
+func (c *watchingClient) Update(ctx context.Context, obj runtime.Object) error {
+	old := obj.DeepCopyObject()
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+	if err := c.Client.Get(ctx, key, old); err != nil {
+		return err
+	}
+	if err := c.Client.Update(ctx, obj); err != nil {
+		return err
+	}
+	return c.notify(obj, func(i *controllertest.FakeInformer) {
+		i.Update(old.(metav1.Object), obj.DeepCopyObject().(metav1.Object))
+	})
+}
+
+func (c *watchingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOptionFunc) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	deleted := obj.DeepCopyObject()
+	key := types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+	if err := c.Client.Get(ctx, key, deleted); err != nil {
+		return err
+	}
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	err = c.notify(obj, func(i *controllertest.FakeInformer) {
+		i.Delete(deleted.(metav1.Object))
+	})
+	if err != nil {
+		return err
+	}
+	return c.collectGarbage(ctx, deleted.(metav1.Object))
+}
+
+// notify sends an event to the informer of the kind of obj.
+func (c *watchingClient) notify(obj runtime.Object, send func(i *controllertest.FakeInformer)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	informer, err := c.informers.FakeInformerFor(obj)
+	if err != nil {
+		return err
+	}
+	send(informer)
+	return nil
+}
+
+func (c *watchingClient) Status() client.StatusWriter {
+	// The fake status writer updates the whole object.
+	return c
+}
+
+// collectGarbage deletes the objects of the kinds created by the controller
+// that are owned by owner.
+func (c *watchingClient) collectGarbage(ctx context.Context, owner metav1.Object) error {
+	lists := []runtime.Object{
+		&appsv1.StatefulSetList{},
+		&corev1.ServiceList{},
+		&corev1.PersistentVolumeClaimList{},
+	}
+	for _, list := range lists {
+		if err := c.List(ctx, client.InNamespace(owner.GetNamespace()), list); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			accessor, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			for _, ref := range accessor.GetOwnerReferences() {
+				if ref.UID != owner.GetUID() {
+					continue
+				}
+				if err := c.Delete(ctx, item); err != nil && !errors.IsNotFound(err) {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// eventually polls check until it succeeds, and fails the test if it doesn't
+// within a few seconds.
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if err = check(); err == nil {
+			return
+		}
+	}
+	t.Fatal(err)
+}
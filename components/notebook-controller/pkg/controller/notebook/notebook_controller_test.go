@@ -0,0 +1,455 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/culler"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func init() {
+	// The fake client decodes objects with the client-go scheme.
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+var testKey = types.NamespacedName{Name: "nb", Namespace: "test"}
+
+func newTestNotebook() *v1alpha1.Notebook {
+	return &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              testKey.Name,
+			Namespace:         testKey.Namespace,
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: v1alpha1.NotebookSpec{
+			Template: v1alpha1.NotebookTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "nb", Image: "jupyter:1"}},
+			}},
+		},
+	}
+}
+
+func newTestReconciler(c client.Client) *ReconcileNotebook {
+	return &ReconcileNotebook{
+		Client:         c,
+		scheme:         scheme.Scheme,
+		recorder:       record.NewFakeRecorder(100),
+		routing:        routing.NewRegistry("ambassador", routing.Ambassador{}),
+		now:            time.Now,
+		readyNotebooks: map[types.NamespacedName]types.UID{},
+	}
+}
+
+// reconcileNotebook runs a reconcile and returns the resulting notebook and StatefulSet.
+func reconcileNotebook(t *testing.T, r *ReconcileNotebook) (*v1alpha1.Notebook, *appsv1.StatefulSet) {
+	t.Helper()
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	nb := &v1alpha1.Notebook{}
+	if err := r.Get(context.TODO(), testKey, nb); err != nil {
+		t.Fatal(err)
+	}
+	ss := &appsv1.StatefulSet{}
+	if err := r.Get(context.TODO(), testKey, ss); err != nil {
+		t.Fatal(err)
+	}
+	return nb, ss
+}
+
+func updateNotebook(t *testing.T, c client.Client, update func(nb *v1alpha1.Notebook)) {
+	t.Helper()
+	nb := &v1alpha1.Notebook{}
+	if err := c.Get(context.TODO(), testKey, nb); err != nil {
+		t.Fatal(err)
+	}
+	update(nb)
+	if err := c.Update(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// apiServerClient ignores the status of notebooks on Update and returns the
+// stored status instead, as the API server does for resources with a status
+// subresource. The fake client keeps the status of the object.
+type apiServerClient struct {
+	client.Client
+}
+
+func (c apiServerClient) Update(ctx context.Context, obj runtime.Object) error {
+	if nb, ok := obj.(*v1alpha1.Notebook); ok {
+		stored := &v1alpha1.Notebook{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}, stored); err != nil {
+			return err
+		}
+		nb.Status = stored.Status
+	}
+	return c.Client.Update(ctx, obj)
+}
+
+// setStatefulSetReplicas sets the observed replicas, as the StatefulSet controller would.
+func setStatefulSetReplicas(t *testing.T, c client.Client, replicas int32) {
+	t.Helper()
+	ss := &appsv1.StatefulSet{}
+	if err := c.Get(context.TODO(), testKey, ss); err != nil {
+		t.Fatal(err)
+	}
+	ss.Status.Replicas = replicas
+	if err := c.Status().Update(context.TODO(), ss); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkCondition(t *testing.T, nb *v1alpha1.Notebook, condType v1alpha1.NotebookConditionType, status corev1.ConditionStatus, reason string) {
+	t.Helper()
+	cond := getCondition(&nb.Status, condType)
+	if cond == nil {
+		t.Errorf("condition %v not set", condType)
+		return
+	}
+	if cond.Status != status || cond.Reason != reason {
+		t.Errorf("condition %v = %v/%v; want %v/%v", condType, cond.Status, cond.Reason, status, reason)
+	}
+}
+
+func TestStopStart(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+
+	nb, ss := reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 {
+		t.Errorf("replicas of a running notebook = %v; want 1", *ss.Spec.Replicas)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionFalse, "Running")
+	setStatefulSetReplicas(t, c, 1)
+
+	// Stop the notebook. It is Stopping until the Pod is gone.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.Stopped = true })
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 0 {
+		t.Errorf("replicas of a stopped notebook = %v; want 0", *ss.Spec.Replicas)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionFalse, "Stopping")
+
+	setStatefulSetReplicas(t, c, 0)
+	nb, _ = reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionTrue, "Stopped")
+	checkCondition(t, nb, v1alpha1.NotebookReady, corev1.ConditionFalse, "Stopped")
+
+	// A new template is rolled out to the StatefulSet, but not started.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+		nb.Spec.Template.Spec.Containers[0].Image = "jupyter:2"
+	})
+	_, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 0 {
+		t.Errorf("replicas after a template change of a stopped notebook = %v; want 0", *ss.Spec.Replicas)
+	}
+	if image := ss.Spec.Template.Spec.Containers[0].Image; image != "jupyter:2" {
+		t.Errorf("image of the stopped StatefulSet = %v; want jupyter:2", image)
+	}
+
+	// Resume the notebook.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.Stopped = false })
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 {
+		t.Errorf("replicas of a resumed notebook = %v; want 1", *ss.Spec.Replicas)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionFalse, "Running")
+}
+
+type fixedSource struct {
+	last time.Time
+}
+
+func (s *fixedSource) LastActivity(nb *v1alpha1.Notebook) (time.Time, error) {
+	return s.last, nil
+}
+
+func TestResumeCountsAsActivity(t *testing.T) {
+	now := time.Now()
+	nb := newTestNotebook()
+	nb.CreationTimestamp = metav1.NewTime(now.Add(-48 * time.Hour))
+	nb.Spec.Stopped = true
+	c := fake.NewFakeClient(nb)
+	r := newTestReconciler(c)
+	r.culler = culler.New(&fixedSource{last: now.Add(-48 * time.Hour)}, time.Hour, time.Minute)
+	r.culler.Now = func() time.Time { return now }
+
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("a stopped notebook was requeued to poll its activity")
+	}
+
+	// The notebook was idle for two days before it was stopped, but is not
+	// culled when it is resumed.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.Stopped = false })
+	nb, ss := reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 {
+		t.Errorf("replicas of a resumed notebook = %v; want 1", *ss.Spec.Replicas)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookCulled, corev1.ConditionFalse, "Active")
+}
+
+func TestEvents(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+
+	reconcileNotebook(t, r)
+	// Someone scales the StatefulSet by hand.
+	ss := &appsv1.StatefulSet{}
+	if err := c.Get(context.TODO(), testKey, ss); err != nil {
+		t.Fatal(err)
+	}
+	replicas := int32(3)
+	ss.Spec.Replicas = &replicas
+	if err := c.Update(context.TODO(), ss); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNotebook(t, r)
+
+	want := []string{
+		"Normal Created Created StatefulSet nb",
+		"Normal Created Created Service nb",
+		"Normal Updated Updated StatefulSet nb to match the notebook spec",
+	}
+	for _, w := range want {
+		select {
+		case got := <-recorder.Events:
+			if got != w {
+				t.Errorf("event = %q; want %q", got, w)
+			}
+		default:
+			t.Errorf("missing event %q", w)
+		}
+	}
+}
+
+func TestFixturesMatchCRDSchema(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Workspace = &v1alpha1.WorkspaceSpec{
+		Size:         resource.MustParse("10Gi"),
+		AccessMode:   corev1.ReadWriteOnce,
+		RetainPolicy: v1alpha1.WorkspaceDelete,
+	}
+	nb.Spec.Schedule = &v1alpha1.ScheduleSpec{Start: "0 8 * * 1-5", Stop: "0 20 * * 1-5", TimeZone: "Europe/Berlin"}
+	nb.Spec.ServiceAccount = &v1alpha1.ServiceAccountSpec{RoleTemplate: "notebook-editor"}
+	nb.Spec.ExposedPorts = []string{"tensorboard"}
+	nb.Spec.DisableDefaultProbes = true
+	checkSchema(t, nb)
+
+	// The status written by the controller.
+	r := newTestReconciler(fake.NewFakeClient(newTestNotebook()))
+	nb, _ = reconcileNotebook(t, r)
+	checkSchema(t, nb)
+
+	max := int32(3)
+	checkSchema(t, &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: testKey.Namespace},
+		Spec: v1alpha1.NotebookPolicySpec{
+			MaxNotebooks:  &max,
+			MaxResources:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+			AllowedImages: []string{"gcr.io/kubeflow-images-public/*"},
+		},
+	})
+}
+
+// newIntegrationNotebook returns a notebook in its own namespace, so the
+// integration tests don't see each other's objects.
+func newIntegrationNotebook(namespace string) *v1alpha1.Notebook {
+	nb := newTestNotebook()
+	nb.Namespace = namespace
+	nb.CreationTimestamp = metav1.Time{}
+	return nb
+}
+
+// getOwned gets the object key of the kind of obj, and checks that it is
+// controlled by nb.
+func getOwned(key types.NamespacedName, obj runtime.Object, nb *v1alpha1.Notebook) error {
+	if err := testClient.Get(context.TODO(), key, obj); err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	ref := metav1.GetControllerOf(accessor)
+	if ref == nil || ref.UID != nb.UID || ref.Kind != "Notebook" {
+		return fmt.Errorf("%T %v is controlled by %+v; want notebook %v", obj, key, ref, nb.UID)
+	}
+	return nil
+}
+
+func TestIntegrationCreateAndUpdate(t *testing.T) {
+	nb := newIntegrationNotebook("integration-update")
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), nb)
+
+	ss := &appsv1.StatefulSet{}
+	eventually(t, func() error { return getOwned(key, ss, nb) })
+	eventually(t, func() error { return getOwned(key, &corev1.Service{}, nb) })
+	if *ss.Spec.Replicas != 1 || ss.Spec.Template.Spec.Containers[0].Image != "jupyter:1" {
+		t.Errorf("StatefulSet replicas = %v, image = %v; want 1, jupyter:1",
+			*ss.Spec.Replicas, ss.Spec.Template.Spec.Containers[0].Image)
+	}
+	eventually(t, func() error {
+		if err := testClient.Get(context.TODO(), key, nb); err != nil {
+			return err
+		}
+		if getCondition(&nb.Status, v1alpha1.NotebookStopped) == nil {
+			return fmt.Errorf("status of the notebook is not set: %+v", nb.Status)
+		}
+		return nil
+	})
+
+	// A new image is rolled out to the StatefulSet.
+	nb.Spec.Template.Spec.Containers[0].Image = "jupyter:2"
+	if err := testClient.Update(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error {
+		if err := getOwned(key, ss, nb); err != nil {
+			return err
+		}
+		if image := ss.Spec.Template.Spec.Containers[0].Image; image != "jupyter:2" {
+			return fmt.Errorf("image of the StatefulSet = %v; want jupyter:2", image)
+		}
+		return nil
+	})
+
+	// Manual changes of the StatefulSet are undone.
+	replicas := int32(3)
+	ss.Spec.Replicas = &replicas
+	if err := testClient.Update(context.TODO(), ss); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error {
+		if err := getOwned(key, ss, nb); err != nil {
+			return err
+		}
+		if *ss.Spec.Replicas != 1 {
+			return fmt.Errorf("replicas of the StatefulSet = %v; want 1", *ss.Spec.Replicas)
+		}
+		return nil
+	})
+}
+
+func TestIntegrationDeletedServiceIsRecreated(t *testing.T) {
+	nb := newIntegrationNotebook("integration-recreate")
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), nb)
+	svc := &corev1.Service{}
+	eventually(t, func() error { return getOwned(key, svc, nb) })
+
+	if err := testClient.Delete(context.TODO(), svc); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error { return getOwned(key, &corev1.Service{}, nb) })
+}
+
+func TestIntegrationDeletedRouteIsRecreated(t *testing.T) {
+	nb := newIntegrationNotebook("integration-route")
+	nb.Annotations = map[string]string{routing.ProviderAnnotation: "ingress"}
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), nb)
+	ingress := &extv1beta1.Ingress{}
+	eventually(t, func() error { return getOwned(key, ingress, nb) })
+
+	if err := testClient.Delete(context.TODO(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error { return getOwned(key, &extv1beta1.Ingress{}, nb) })
+}
+
+func TestIntegrationGarbageCollection(t *testing.T) {
+	nb := newIntegrationNotebook("integration-gc")
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+
+	// The garbage collector deletes the objects of the notebook with it, and
+	// waits for them in a foreground deletion, by their owner references.
+	owned := []runtime.Object{&appsv1.StatefulSet{}, &corev1.Service{}}
+	for _, obj := range owned {
+		eventually(t, func() error { return getOwned(key, obj, nb) })
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := metav1.GetControllerOf(accessor)
+		if ref.APIVersion != v1alpha1.SchemeGroupVersion.String() || ref.Name != nb.Name ||
+			ref.BlockOwnerDeletion == nil || !*ref.BlockOwnerDeletion {
+			t.Errorf("%T owner reference = %+v; want the notebook, blocking its deletion", obj, ref)
+		}
+	}
+
+	if err := testClient.Delete(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	// The controller doesn't recreate the objects of the deleted notebook. It
+	// handles the deletion before the notebooks created later.
+	next := newIntegrationNotebook(nb.Namespace)
+	next.Name = "next"
+	if err := testClient.Create(context.TODO(), next); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), next)
+	nextKey := types.NamespacedName{Namespace: next.Namespace, Name: next.Name}
+	eventually(t, func() error { return getOwned(nextKey, &appsv1.StatefulSet{}, next) })
+	for _, obj := range owned {
+		if err := testClient.Get(context.TODO(), key, obj); !errors.IsNotFound(err) {
+			t.Errorf("%T of the deleted notebook was recreated: err = %v", obj, err)
+		}
+	}
+}
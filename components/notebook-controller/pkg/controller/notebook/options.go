@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"flag"
+	"time"
+)
+
+// Options configures the Notebook controller.
+type Options struct {
+	// EnableCulling scales notebooks to zero replicas after they have been idle for IdleTime.
+	EnableCulling bool
+	IdleTime      time.Duration
+	// CullingCheckPeriod is how often the activity of running notebooks is checked.
+	CullingCheckPeriod time.Duration
+	// RoutingProvider is the name of the routing provider used for notebooks
+	// that don't select one with an annotation.
+	RoutingProvider string
+	// IstioGateway is the <namespace>/<name> of the gateway Istio VirtualServices bind to.
+	IstioGateway string
+	// IngressClass is the class of the Ingresses created for notebooks, if any.
+	IngressClass string
+	// SidecarConfigMap is the <namespace>/<name> of the ConfigMap of the sidecar
+	// definitions. Sidecar injection is disabled if it is empty.
+	SidecarConfigMap string
+	// DefaultProbes adds readiness and liveness probes of the Jupyter API to
+	// the notebook containers that have none.
+	DefaultProbes bool
+	// CleanupHooks are the comma-separated cleanup hooks run before deleted
+	// notebooks are released: names of in-process hooks or webhook URLs.
+	// Notebooks get no finalizer if it is empty.
+	CleanupHooks string
+	// CleanupHookTimeout is how long each cleanup hook may take.
+	CleanupHookTimeout time.Duration
+}
+
+// DefaultOptions are the options used by Add. The manager sets them from its
+// command line flags before adding the controller.
+var DefaultOptions = Options{
+	IdleTime:           24 * time.Hour,
+	CullingCheckPeriod: time.Minute,
+	RoutingProvider:    "ambassador",
+	IstioGateway:       "kubeflow/kubeflow-gateway",
+	DefaultProbes:      true,
+	CleanupHookTimeout: 30 * time.Second,
+}
+
+// AddFlags registers the controller options with fs.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.EnableCulling, "enable-culling", o.EnableCulling, "Scale idle notebooks down to zero replicas.")
+	fs.DurationVar(&o.IdleTime, "idle-time", o.IdleTime, "How long a notebook may be idle before it is culled.")
+	fs.DurationVar(&o.CullingCheckPeriod, "culling-check-period", o.CullingCheckPeriod, "How often the activity of running notebooks is checked.")
+	fs.StringVar(&o.RoutingProvider, "routing-provider", o.RoutingProvider, "How notebooks are exposed: ambassador, istio or ingress.")
+	fs.StringVar(&o.IstioGateway, "istio-gateway", o.IstioGateway, "The <namespace>/<name> of the Istio gateway used by the istio routing provider.")
+	fs.StringVar(&o.IngressClass, "ingress-class", o.IngressClass, "The ingress class used by the ingress routing provider.")
+	fs.StringVar(&o.SidecarConfigMap, "sidecar-configmap", o.SidecarConfigMap, "The <namespace>/<name> of the ConfigMap of the sidecars injected into notebooks. Sidecars are not injected if empty.")
+	fs.StringVar(&o.CleanupHooks, "cleanup-hooks", o.CleanupHooks, "Comma-separated hooks run before deleted notebooks are released: names of in-process hooks or http(s) URLs of webhooks.")
+	fs.DurationVar(&o.CleanupHookTimeout, "cleanup-hook-timeout", o.CleanupHookTimeout, "How long each cleanup hook may take.")
+	fs.BoolVar(&o.DefaultProbes, "default-probes", o.DefaultProbes, "Add readiness and liveness probes of the Jupyter API to notebook containers that have none. Changing it restarts all notebooks.")
+}
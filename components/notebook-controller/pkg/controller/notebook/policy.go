@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"sort"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReconcilePolicy sets the PolicyViolated condition of the notebook running
+// podSpec. New notebooks are checked by the validating webhook, so violations
+// come from policies or templates that changed after the notebook was created.
+// The violating notebook keeps running. The status itself is written by Reconcile.
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebookpolicies,verbs=get;list;watch
+func (r *ReconcileNotebook) ReconcilePolicy(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) error {
+	policies, err := policy.List(context.TODO(), r.Client, instance.Namespace)
+	if err != nil {
+		return err
+	}
+	index := 0
+	if policy.HasMaxNotebooks(policies) {
+		if index, err = r.notebookIndex(instance); err != nil {
+			return err
+		}
+	}
+	errs := field.ErrorList{}
+	for i := range policies {
+		errs = append(errs, policy.Check(&policies[i], podSpec)...)
+		if err := policy.CheckCount(&policies[i], index); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cond := v1alpha1.NotebookCondition{
+		Type:   v1alpha1.NotebookPolicyViolated,
+		Status: corev1.ConditionFalse,
+		Reason: "Compliant",
+	}
+	if len(errs) > 0 {
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = "PolicyViolated"
+		cond.Message = errs.ToAggregate().Error()
+	}
+	if setCondition(&instance.Status, cond) && cond.Status == corev1.ConditionTrue {
+		r.recorder.Event(instance, corev1.EventTypeWarning, reasonPolicyViolated, cond.Message)
+	}
+	return nil
+}
+
+// notebookIndex returns the position of the notebook among the notebooks of
+// its namespace, oldest first.
+func (r *ReconcileNotebook) notebookIndex(instance *v1alpha1.Notebook) (int, error) {
+	notebooks := &v1alpha1.NotebookList{}
+	if err := r.List(context.TODO(), client.InNamespace(instance.Namespace), notebooks); err != nil {
+		return 0, err
+	}
+	items := notebooks.Items
+	sort.Slice(items, func(i, j int) bool {
+		ti, tj := items[i].CreationTimestamp, items[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return items[i].Name < items[j].Name
+	})
+	for i := range items {
+		if items[i].Name == instance.Name {
+			return i, nil
+		}
+	}
+	return len(items), nil
+}
+
+// notebooksForPolicy maps a policy to the notebooks of its namespace, so they are
+// checked again when it changes.
+func notebooksForPolicy(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		return notebookRequests(c, a.Meta.GetNamespace())
+	}
+}
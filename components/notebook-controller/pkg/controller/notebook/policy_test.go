@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestIntegrationPolicy(t *testing.T) {
+	nb := newIntegrationNotebook("integration-policy")
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), nb)
+
+	// checkPolicy waits for the PolicyViolated condition to have the status.
+	checkPolicy := func(status corev1.ConditionStatus) *v1alpha1.NotebookCondition {
+		var cond *v1alpha1.NotebookCondition
+		eventually(t, func() error {
+			if err := testClient.Get(context.TODO(), key, nb); err != nil {
+				return err
+			}
+			cond = getCondition(&nb.Status, v1alpha1.NotebookPolicyViolated)
+			if cond == nil || cond.Status != status {
+				return fmt.Errorf("PolicyViolated condition = %+v; want status %v", cond, status)
+			}
+			return nil
+		})
+		return cond
+	}
+	checkPolicy(corev1.ConditionFalse)
+
+	// A policy created after the notebook reports the violations of the notebook.
+	p := &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: nb.Namespace},
+		Spec:       v1alpha1.NotebookPolicySpec{AllowedImages: []string{"gcr.io/kubeflow-images-public/*"}},
+	}
+	if err := testClient.Create(context.TODO(), p); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), p)
+	cond := checkPolicy(corev1.ConditionTrue)
+	if !strings.Contains(cond.Message, "is not allowed by NotebookPolicy default") {
+		t.Errorf("PolicyViolated message = %q; want the disallowed image", cond.Message)
+	}
+
+	// Allowing the image of the notebook clears the condition.
+	p.Spec.AllowedImages = append(p.Spec.AllowedImages, nb.Spec.Template.Spec.Containers[0].Image)
+	if err := testClient.Update(context.TODO(), p); err != nil {
+		t.Fatal(err)
+	}
+	checkPolicy(corev1.ConditionFalse)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// nbPrefixEnv is the environment variable the Kubeflow notebook images start
+// the Jupyter server with as its base URL.
+const nbPrefixEnv = "NB_PREFIX"
+
+// serverBaseURL returns the base URL of the Jupyter server of the notebook
+// container: NB_PREFIX if the container sets it, routing.BaseURL otherwise.
+func serverBaseURL(instance *v1alpha1.Notebook, container *corev1.Container) string {
+	for _, env := range container.Env {
+		if env.Name == nbPrefixEnv && env.Value != "" {
+			return strings.TrimSuffix(env.Value, "/")
+		}
+	}
+	return routing.BaseURL(instance)
+}
+
+// jupyterAPIProbe returns a probe of the Jupyter API of the notebook, which
+// answers without authentication under the base URL of the server.
+func jupyterAPIProbe(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: serverBaseURL(instance, &podSpec.Containers[0]) + "/api",
+				Port: intstr.FromInt(routing.NotebookContainerPort(podSpec)),
+			},
+		},
+		TimeoutSeconds:   5,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+}
+
+// addDefaultProbes adds readiness and liveness probes of the Jupyter API to the
+// notebook container, unless it has its own or the notebook disables them. The
+// readiness probe makes the Ready condition reflect the Jupyter server. The
+// liveness probe restarts a server that stopped answering, and is lenient so
+// that a busy server isn't restarted.
+func (r *ReconcileNotebook) addDefaultProbes(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) {
+	if !r.defaultProbes || instance.Spec.DisableDefaultProbes || len(podSpec.Containers) == 0 {
+		return
+	}
+	container := &podSpec.Containers[0]
+	if container.ReadinessProbe == nil {
+		container.ReadinessProbe = jupyterAPIProbe(instance, podSpec)
+	}
+	if container.LivenessProbe == nil {
+		probe := jupyterAPIProbe(instance, podSpec)
+		probe.InitialDelaySeconds = 60
+		probe.PeriodSeconds = 30
+		probe.FailureThreshold = 5
+		container.LivenessProbe = probe
+	}
+}
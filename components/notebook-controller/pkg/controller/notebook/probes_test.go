@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDefaultProbes(t *testing.T) {
+	customProbe := &corev1.Probe{Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"true"}}}}
+	tests := []struct {
+		name          string
+		update        func(nb *v1alpha1.Notebook)
+		wantReadiness string
+		wantLiveness  string
+	}{
+		{
+			name:          "default",
+			update:        func(nb *v1alpha1.Notebook) {},
+			wantReadiness: "/test/nb/api:8888",
+			wantLiveness:  "/test/nb/api:8888",
+		},
+		{
+			name: "custom port",
+			update: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}
+			},
+			wantReadiness: "/test/nb/api:8080",
+			wantLiveness:  "/test/nb/api:8080",
+		},
+		{
+			name: "other notebook",
+			update: func(nb *v1alpha1.Notebook) {
+				nb.Namespace = "team-a"
+				nb.Name = "analysis"
+			},
+			wantReadiness: "/team-a/analysis/api:8888",
+			wantLiveness:  "/team-a/analysis/api:8888",
+		},
+		{
+			name: "NB_PREFIX",
+			update: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "NB_PREFIX", Value: "/lab/nb/"}}
+			},
+			wantReadiness: "/lab/nb/api:8888",
+			wantLiveness:  "/lab/nb/api:8888",
+		},
+		{
+			name: "own readiness probe",
+			update: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers[0].ReadinessProbe = customProbe
+			},
+			wantReadiness: "exec",
+			wantLiveness:  "/test/nb/api:8888",
+		},
+		{
+			name:   "disabled",
+			update: func(nb *v1alpha1.Notebook) { nb.Spec.DisableDefaultProbes = true },
+		},
+	}
+	describe := func(p *corev1.Probe) string {
+		switch {
+		case p == nil:
+			return ""
+		case p.HTTPGet != nil:
+			return p.HTTPGet.Path + ":" + p.HTTPGet.Port.String()
+		case p.Exec != nil:
+			return "exec"
+		}
+		return "other"
+	}
+	for _, test := range tests {
+		nb := newTestNotebook()
+		test.update(nb)
+		r := newTestReconciler(fake.NewFakeClient(nb))
+		r.defaultProbes = true
+		key := types.NamespacedName{Name: nb.Name, Namespace: nb.Namespace}
+		if _, err := r.Reconcile(reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%v: Reconcile: %v", test.name, err)
+		}
+		ss := &appsv1.StatefulSet{}
+		if err := r.Get(context.TODO(), key, ss); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		container := ss.Spec.Template.Spec.Containers[0]
+		if got := describe(container.ReadinessProbe); got != test.wantReadiness {
+			t.Errorf("%v: readiness probe = %q; want %q", test.name, got, test.wantReadiness)
+		}
+		if got := describe(container.LivenessProbe); got != test.wantLiveness {
+			t.Errorf("%v: liveness probe = %q; want %q", test.name, got, test.wantLiveness)
+		}
+	}
+}
+
+func TestReadyConditionServerNotReady(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+	reconcileNotebook(t, r)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: testKey.Name + "-0", Namespace: testKey.Namespace},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodReady,
+				Status: corev1.ConditionFalse,
+				Reason: "ContainersNotReady",
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "nb",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+	if err := c.Create(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	nb, _ := reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookReady, corev1.ConditionFalse, "ServerNotReady")
+
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	pod.Status.ContainerStatuses[0].Ready = true
+	if err := c.Update(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	nb, _ = reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookReady, corev1.ConditionTrue, "Running")
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// notebookPorts returns the ports of the notebook running podSpec that are
+// routed to, and records a warning event for each exposed port that is skipped.
+func (r *ReconcileNotebook) notebookPorts(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) []routing.Port {
+	ports, problems := routing.Ports(instance, podSpec)
+	for _, problem := range problems {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, reasonInvalidSpec, "Port not exposed: %v", problem)
+	}
+	return ports
+}
+
+// ReconcileRoute reconciles the routing objects of the provider selected for the
+// notebook, one per port or one for all ports depending on the provider, and
+// deletes the other routing objects of the notebook, e.g. of a previous
+// provider or of a port that is no longer exposed. It sets the URLs of the
+// ports in the status, which tell the next reconcile which ports were routed.
+// +kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+func (r *ReconcileNotebook) ReconcileRoute(instance *v1alpha1.Notebook, ports []routing.Port) error {
+	provider, err := r.routing.ProviderFor(instance)
+	if err != nil {
+		return err
+	}
+	routes, err := provider.Routes(instance, ports)
+	if err != nil {
+		return err
+	}
+
+	desired := map[schema.GroupVersionKind]map[string]bool{}
+	for _, route := range routes {
+		gvk := route.GroupVersionKind()
+		if desired[gvk] == nil {
+			desired[gvk] = map[string]bool{}
+		}
+		desired[gvk][route.GetName()] = true
+	}
+	// The routing objects are named after the notebook or its ports.
+	names := []string{routing.RouteName(instance, routing.NotebookPortName)}
+	for _, u := range instance.Status.URLs {
+		if u.Name != routing.NotebookPortName {
+			names = append(names, routing.RouteName(instance, u.Name))
+		}
+	}
+	for _, gvk := range routing.RouteKinds {
+		for _, name := range names {
+			if desired[gvk][name] {
+				continue
+			}
+			if err := r.deleteRoute(instance, gvk, name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, route := range routes {
+		if err := r.reconcileRouteObject(instance, route); err != nil {
+			return err
+		}
+	}
+
+	instance.Status.URLs = nil
+	for _, p := range ports {
+		instance.Status.URLs = append(instance.Status.URLs, v1alpha1.NotebookURL{Name: p.Name, URL: p.URL()})
+	}
+	return nil
+}
+
+// watchRoutes reconciles notebooks when their routing objects change. Kinds
+// that aren't installed in the cluster, e.g. VirtualServices without Istio, are
+// not watched.
+func watchRoutes(c controller.Controller, mgr manager.Manager) error {
+	for _, gvk := range routing.RouteKinds {
+		_, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			log.Info("Not watching routing objects of a kind that isn't installed", "kind", gvk.String())
+			continue
+		} else if err != nil {
+			return err
+		}
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(gvk)
+		err = c.Watch(&source.Kind{Type: route}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &v1alpha1.Notebook{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileRouteObject creates or updates a routing object of the notebook.
+func (r *ReconcileNotebook) reconcileRouteObject(instance *v1alpha1.Notebook, route *unstructured.Unstructured) error {
+	if err := controllerutil.SetControllerReference(instance, route, r.scheme); err != nil {
+		return err
+	}
+
+	// Check if the routing object already exists
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(route.GroupVersionKind())
+	err := r.Get(context.TODO(), types.NamespacedName{Name: route.GetName(), Namespace: route.GetNamespace()}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating "+route.GetKind(), "namespace", route.GetNamespace(), "name", route.GetName())
+		if err := r.Create(context.TODO(), route); err != nil {
+			return err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created %v %v", route.GetKind(), route.GetName())
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Update the found object and write the result back if there are any changes
+	if !equality.Semantic.DeepEqual(route.Object["spec"], found.Object["spec"]) ||
+		!equality.Semantic.DeepEqual(route.GetAnnotations(), found.GetAnnotations()) {
+		found.Object["spec"] = route.Object["spec"]
+		found.SetAnnotations(route.GetAnnotations())
+		log.Info("Updating "+route.GetKind(), "namespace", route.GetNamespace(), "name", route.GetName())
+		if err := r.Update(context.TODO(), found); err != nil {
+			return err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonUpdated, "Updated %v %v to match the notebook spec", route.GetKind(), route.GetName())
+	}
+	return nil
+}
+
+// deleteRoute deletes the routing object of the given kind and name if it was
+// created for the notebook.
+func (r *ReconcileNotebook) deleteRoute(instance *v1alpha1.Notebook, gvk schema.GroupVersionKind, name string) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(gvk)
+	err := r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: instance.Namespace}, found)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		// Nothing to delete, or the kind isn't installed in the cluster.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(found, instance) {
+		return nil
+	}
+	log.Info("Deleting "+gvk.Kind, "namespace", found.GetNamespace(), "name", found.GetName())
+	if err := r.Delete(context.TODO(), found); err != nil {
+		return err
+	}
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonDeleted, "Deleted %v %v that no longer routes to the notebook", gvk.Kind, found.GetName())
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileExposedPorts(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
+		{Name: "notebook-port", ContainerPort: 8888},
+		{Name: "tensorboard", ContainerPort: 6006},
+	}
+	nb.Spec.ExposedPorts = []string{"tensorboard", "missing"}
+	c := fake.NewFakeClient(nb)
+	r := newTestReconciler(c)
+	r.routing = routing.NewRegistry("ingress", &routing.Ingress{})
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+
+	nb, _ = reconcileNotebook(t, r)
+	svc := &corev1.Service{}
+	if err := c.Get(context.TODO(), testKey, svc); err != nil {
+		t.Fatal(err)
+	}
+	var svcPorts []string
+	for _, p := range svc.Spec.Ports {
+		svcPorts = append(svcPorts, p.Name+":"+p.TargetPort.String())
+	}
+	if want := []string{"http-notebook:8888", "http-tensorboard:6006"}; !reflect.DeepEqual(svcPorts, want) {
+		t.Errorf("Service ports = %v; want %v", svcPorts, want)
+	}
+	wantURLs := []v1alpha1.NotebookURL{
+		{Name: "notebook", URL: "/notebook/test/nb/"},
+		{Name: "tensorboard", URL: "/notebook/test/nb/ports/tensorboard/"},
+	}
+	if !reflect.DeepEqual(nb.Status.URLs, wantURLs) {
+		t.Errorf("URLs = %+v; want %+v", nb.Status.URLs, wantURLs)
+	}
+	if want := "Warning InvalidSpec Port not exposed: the notebook has no container port named missing"; !hasEvent(recorder, want) {
+		t.Errorf("missing event %q", want)
+	}
+	portKey := types.NamespacedName{Namespace: testKey.Namespace, Name: "nb-tensorboard"}
+	if err := c.Get(context.TODO(), portKey, &extv1beta1.Ingress{}); err != nil {
+		t.Errorf("Ingress of the exposed port: %v", err)
+	}
+
+	// The route of a port that is no longer exposed is deleted.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.ExposedPorts = nil })
+	nb, _ = reconcileNotebook(t, r)
+	if err := c.Get(context.TODO(), portKey, &extv1beta1.Ingress{}); !errors.IsNotFound(err) {
+		t.Errorf("Ingress of the port no longer exposed: err = %v; want NotFound", err)
+	}
+	if err := c.Get(context.TODO(), testKey, &extv1beta1.Ingress{}); err != nil {
+		t.Errorf("Ingress of the notebook server: %v", err)
+	}
+	if len(nb.Status.URLs) != 1 {
+		t.Errorf("URLs = %+v; want the notebook server only", nb.Status.URLs)
+	}
+}
+
+// getVirtualService gets the VirtualService key, which the Istio types are not
+// vendored for.
+func getVirtualService(c client.Client, key types.NamespacedName) (*unstructured.Unstructured, error) {
+	vs := &unstructured.Unstructured{}
+	vs.SetAPIVersion("networking.istio.io/v1alpha3")
+	vs.SetKind("VirtualService")
+	err := c.Get(context.TODO(), key, vs)
+	return vs, err
+}
+
+func TestReconcileRouteProviders(t *testing.T) {
+	c := fake.NewFakeClient(newTestNotebook())
+	r := newTestReconciler(c)
+	r.routing = routing.NewRegistry("ambassador",
+		routing.Ambassador{},
+		&routing.Istio{Gateway: "kubeflow/kubeflow-gateway"},
+		&routing.Ingress{})
+	const ambassadorConfig = "getambassador.io/config"
+
+	// checkRoutes checks which routing objects of the notebook exist.
+	checkRoutes := func(provider string, wantVirtualService, wantIngress, wantMapping bool) {
+		t.Helper()
+		nb, _ := reconcileNotebook(t, r)
+		vs, err := getVirtualService(c, testKey)
+		if wantVirtualService {
+			if err != nil {
+				t.Errorf("%s: VirtualService: %v", provider, err)
+			} else if !metav1.IsControlledBy(vs, nb) {
+				t.Errorf("%s: VirtualService not controlled by the notebook", provider)
+			}
+		} else if !errors.IsNotFound(err) {
+			t.Errorf("%s: VirtualService: err = %v; want NotFound", provider, err)
+		}
+		ingress := &extv1beta1.Ingress{}
+		err = c.Get(context.TODO(), testKey, ingress)
+		if wantIngress {
+			if err != nil {
+				t.Errorf("%s: Ingress: %v", provider, err)
+			} else if !metav1.IsControlledBy(ingress, nb) {
+				t.Errorf("%s: Ingress not controlled by the notebook", provider)
+			}
+		} else if !errors.IsNotFound(err) {
+			t.Errorf("%s: Ingress: err = %v; want NotFound", provider, err)
+		}
+		svc := &corev1.Service{}
+		if err := c.Get(context.TODO(), testKey, svc); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := svc.Annotations[ambassadorConfig]; ok != wantMapping {
+			t.Errorf("%s: Service has the Ambassador mapping: %v; want %v", provider, ok, wantMapping)
+		}
+		wantURLs := []v1alpha1.NotebookURL{{Name: "notebook", URL: "/notebook/test/nb/"}}
+		if !reflect.DeepEqual(nb.Status.URLs, wantURLs) {
+			t.Errorf("%s: URLs = %+v; want %+v", provider, nb.Status.URLs, wantURLs)
+		}
+	}
+	setProvider := func(provider string) {
+		updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+			nb.Annotations = map[string]string{routing.ProviderAnnotation: provider}
+		})
+	}
+
+	checkRoutes("ambassador", false, false, true)
+	setProvider("istio")
+	checkRoutes("istio", true, false, false)
+	vs, err := getVirtualService(c, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateways, _, _ := unstructured.NestedStringSlice(vs.Object, "spec", "gateways")
+	if want := []string{"kubeflow/kubeflow-gateway"}; !reflect.DeepEqual(gateways, want) {
+		t.Errorf("gateways of the VirtualService = %v; want %v", gateways, want)
+	}
+
+	// The routing objects of the previous provider are deleted.
+	setProvider("ingress")
+	checkRoutes("ingress", false, true, false)
+	setProvider("ambassador")
+	checkRoutes("ambassador again", false, false, true)
+
+	// Routing objects not controlled by the notebook are left alone.
+	other := &extv1beta1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "extensions/v1beta1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: testKey.Name, Namespace: testKey.Namespace},
+	}
+	if err := c.Create(context.TODO(), other); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNotebook(t, r)
+	if err := c.Get(context.TODO(), testKey, &extv1beta1.Ingress{}); err != nil {
+		t.Errorf("Ingress not created for the notebook: %v", err)
+	}
+
+	setProvider("unknown")
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err == nil {
+		t.Errorf("Reconcile with an unknown provider succeeded")
+	}
+}
+
+// hasEvent reads the recorded events until it finds want.
+func hasEvent(recorder *record.FakeRecorder, want string) bool {
+	for {
+		select {
+		case got := <-recorder.Events:
+			if got == want {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
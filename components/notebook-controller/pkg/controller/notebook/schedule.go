@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/schedule"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reasonScheduled is the reason of the events recorded when the schedule of a
+// notebook starts or stops it.
+const reasonScheduled = "Scheduled"
+
+// ReconcileSchedule sets the schedule status of the notebook at the current
+// time. ReconcileStatefulSet scales notebooks outside the windows of their
+// schedule to zero. It returns how long until the next transition, so the
+// notebook is reconciled again right then, or zero without a schedule.
+// The status itself is written by Reconcile.
+func (r *ReconcileNotebook) ReconcileSchedule(instance *v1alpha1.Notebook) time.Duration {
+	spec := instance.Spec.Schedule
+	if spec == nil {
+		instance.Status.Schedule = nil
+		return 0
+	}
+	w, err := schedule.NewWindow(spec.Start, spec.Stop, spec.TimeZone)
+	if err != nil {
+		// The notebook keeps running while its schedule is invalid.
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, reasonInvalidSpec, "Invalid schedule: %v", err)
+		instance.Status.Schedule = nil
+		return 0
+	}
+
+	now := r.now()
+	running, next := w.At(now)
+	nextTransition := metav1.NewTime(next)
+	if previous := instance.Status.Schedule; previous != nil && previous.Running != running {
+		if running {
+			r.recorder.Event(instance, corev1.EventTypeNormal, reasonScheduled, "Started by the schedule")
+		} else {
+			r.recorder.Event(instance, corev1.EventTypeNormal, reasonScheduled, "Stopped by the schedule")
+		}
+	}
+	instance.Status.Schedule = &v1alpha1.ScheduleStatus{
+		Running:        running,
+		NextTransition: &nextTransition,
+	}
+	return next.Sub(now)
+}
+
+// stoppedBySchedule returns true if the notebook is outside the windows of its schedule.
+func stoppedBySchedule(instance *v1alpha1.Notebook) bool {
+	s := instance.Status.Schedule
+	return s != nil && !s.Running
+}
+
+// isStopped returns true if the notebook is stopped by its spec or its schedule.
+func isStopped(instance *v1alpha1.Notebook) bool {
+	return instance.Spec.Stopped || stoppedBySchedule(instance)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/culler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileSchedule(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Schedule = &v1alpha1.ScheduleSpec{
+		Start:    "0 8 * * MON-FRI",
+		Stop:     "0 20 * * MON-FRI",
+		TimeZone: "Europe/Berlin",
+	}
+	c := fake.NewFakeClient(nb)
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(100)
+	r.recorder = recorder
+	// Friday 19:30 in Berlin.
+	now := time.Date(2019, 2, 1, 18, 30, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	// checkRequeue runs a reconcile and checks when the notebook is reconciled again.
+	checkRequeue := func(wantRequeue time.Duration) {
+		t.Helper()
+		result, err := r.Reconcile(reconcile.Request{NamespacedName: testKey})
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if result.RequeueAfter != wantRequeue {
+			t.Errorf("RequeueAfter = %v; want %v", result.RequeueAfter, wantRequeue)
+		}
+	}
+
+	checkRequeue(30 * time.Minute)
+	nb, ss := reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 {
+		t.Errorf("replicas within the window = %v; want 1", *ss.Spec.Replicas)
+	}
+	wantNext := time.Date(2019, 2, 1, 19, 0, 0, 0, time.UTC)
+	if s := nb.Status.Schedule; s == nil || !s.Running || !s.NextTransition.Time.Equal(wantNext) {
+		t.Errorf("schedule status = %+v; want running until %v", s, wantNext)
+	}
+	setStatefulSetReplicas(t, c, 1)
+
+	// At 20:00 the notebook is stopped until Monday 08:00.
+	now = wantNext
+	checkRequeue(60 * time.Hour)
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 0 {
+		t.Errorf("replicas outside the window = %v; want 0", *ss.Spec.Replicas)
+	}
+	if s := nb.Status.Schedule; s == nil || s.Running {
+		t.Errorf("schedule status = %+v; want not running", s)
+	}
+	if want := "Normal Scheduled Stopped by the schedule"; !hasEvent(recorder, want) {
+		t.Errorf("missing event %q", want)
+	}
+	setStatefulSetReplicas(t, c, 0)
+	nb, _ = reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionTrue, "OutsideSchedule")
+
+	// Stopped takes precedence over the schedule.
+	now = time.Date(2019, 2, 4, 7, 0, 0, 0, time.UTC)
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.Stopped = true })
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 0 {
+		t.Errorf("replicas of a stopped notebook within the window = %v; want 0", *ss.Spec.Replicas)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionTrue, "Stopped")
+
+	// Without a schedule the notebook runs and isn't requeued.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+		nb.Spec.Stopped = false
+		nb.Spec.Schedule = nil
+	})
+	checkRequeue(0)
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 || nb.Status.Schedule != nil {
+		t.Errorf("replicas = %v, schedule status = %+v without a schedule; want 1 and none", *ss.Spec.Replicas, nb.Status.Schedule)
+	}
+}
+
+func TestScheduleStartWithCulling(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Schedule = &v1alpha1.ScheduleSpec{Start: "0 8 * * *", Stop: "0 20 * * *"}
+	// Resuming the notebook updates its activity annotation, which must not
+	// discard the schedule status computed before.
+	c := apiServerClient{fake.NewFakeClient(nb)}
+	r := newTestReconciler(c)
+	now := time.Date(2019, 2, 1, 21, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.culler = culler.New(&fixedSource{last: now}, time.Hour, time.Minute)
+	r.culler.Now = r.now
+
+	_, ss := reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 0 {
+		t.Errorf("replicas outside the window = %v; want 0", *ss.Spec.Replicas)
+	}
+	setStatefulSetReplicas(t, c, 0)
+	nb, _ = reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookStopped, corev1.ConditionTrue, "OutsideSchedule")
+
+	now = time.Date(2019, 2, 2, 8, 0, 0, 0, time.UTC)
+	nb, ss = reconcileNotebook(t, r)
+	if *ss.Spec.Replicas != 1 {
+		t.Errorf("replicas within the window = %v; want 1", *ss.Spec.Replicas)
+	}
+	if s := nb.Status.Schedule; s == nil || !s.Running {
+		t.Errorf("schedule status = %+v; want running", s)
+	}
+}
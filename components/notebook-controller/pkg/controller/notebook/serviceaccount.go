@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// serviceAccountName returns the name of the ServiceAccount of the notebook,
+// which is also the name of its Role and RoleBinding.
+func serviceAccountName(notebookName string) string {
+	return "notebook-" + notebookName
+}
+
+// ReconcileServiceAccount reconciles the ServiceAccount of a notebook with
+// spec.serviceAccount, and the Role and RoleBinding that grant it the rules of
+// its role template. They are owned by the notebook, and deleted when
+// spec.serviceAccount, or its role template, is unset.
+// Creating a Role with the rules of a ClusterRole needs the escalate verb, and
+// binding it the bind verb, unless the controller holds all the permissions.
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete;escalate;bind
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch
+func (r *ReconcileNotebook) ReconcileServiceAccount(instance *v1alpha1.Notebook) error {
+	name := serviceAccountName(instance.Name)
+	if instance.Spec.ServiceAccount == nil {
+		return r.deleteOwned(instance, name, &rbacv1.RoleBinding{}, &rbacv1.Role{}, &corev1.ServiceAccount{})
+	}
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace},
+	}
+	if err := r.createOwned(instance, sa, &corev1.ServiceAccount{}); err != nil {
+		return err
+	}
+
+	rules, err := r.roleTemplateRules(instance)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		return r.deleteOwned(instance, name, &rbacv1.RoleBinding{}, &rbacv1.Role{})
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace},
+		Rules:      rules,
+	}
+	found := &rbacv1.Role{}
+	if err := r.createOwned(instance, role, found); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(found.Rules, role.Rules) {
+		found.Rules = role.Rules
+		log.Info("Updating Role", "namespace", role.Namespace, "name", role.Name)
+		if err := r.Update(context.TODO(), found); err != nil {
+			return err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonUpdated, "Updated Role %v to match ClusterRole %v", role.Name, instance.Spec.ServiceAccount.RoleTemplate)
+	}
+
+	// The subjects and the role of the binding never change.
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      name,
+			Namespace: instance.Namespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     name,
+		},
+	}
+	return r.createOwned(instance, binding, &rbacv1.RoleBinding{})
+}
+
+// roleTemplateRules returns the rules of the role template of the notebook. It
+// returns nil, and records an event, if the notebook has no role template or if
+// the ClusterRole isn't a role template.
+func (r *ReconcileNotebook) roleTemplateRules(instance *v1alpha1.Notebook) ([]rbacv1.PolicyRule, error) {
+	name := instance.Spec.ServiceAccount.RoleTemplate
+	if name == "" {
+		return nil, nil
+	}
+	cr := &rbacv1.ClusterRole{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: name}, cr)
+	if errors.IsNotFound(err) {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, reasonInvalidSpec, "Role template not found: ClusterRole %v", name)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if cr.Labels[v1alpha1.RoleTemplateLabel] != "true" {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, reasonInvalidSpec,
+			"ClusterRole %v is not a role template: it lacks the label %v=true", name, v1alpha1.RoleTemplateLabel)
+		return nil, nil
+	}
+	if cr.Rules == nil {
+		return []rbacv1.PolicyRule{}, nil
+	}
+	return cr.Rules, nil
+}
+
+// createOwned creates obj, owned by the notebook, unless it exists. The
+// existing object is read into found. An object with the same name that isn't
+// owned by the notebook is left alone and reported as an error, so the
+// notebook doesn't get the permissions of a ServiceAccount it doesn't own.
+func (r *ReconcileNotebook) createOwned(instance *v1alpha1.Notebook, obj, found runtime.Object) error {
+	meta := obj.(metav1.Object)
+	kind := reflect.TypeOf(obj).Elem().Name()
+	if err := controllerutil.SetControllerReference(instance, meta, r.scheme); err != nil {
+		return err
+	}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: meta.GetName(), Namespace: meta.GetNamespace()}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating "+kind, "namespace", meta.GetNamespace(), "name", meta.GetName())
+		if err := r.Create(context.TODO(), obj); err != nil {
+			return err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created %v %v", kind, meta.GetName())
+		reflect.ValueOf(found).Elem().Set(reflect.ValueOf(obj).Elem())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(found.(metav1.Object), instance) {
+		return fmt.Errorf("%v %v already exists and is not owned by the notebook", kind, meta.GetName())
+	}
+	return nil
+}
+
+// deleteOwned deletes the objects with the given name that are owned by the notebook.
+func (r *ReconcileNotebook) deleteOwned(instance *v1alpha1.Notebook, name string, objs ...runtime.Object) error {
+	for _, obj := range objs {
+		kind := reflect.TypeOf(obj).Elem().Name()
+		err := r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: instance.Namespace}, obj)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(obj.(metav1.Object), instance) {
+			continue
+		}
+		log.Info("Deleting "+kind, "namespace", instance.Namespace, "name", name)
+		if err := r.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonDeleted, "Deleted %v %v", kind, name)
+	}
+	return nil
+}
+
+// addServiceAccount runs the notebook Pod as the ServiceAccount of the notebook.
+func addServiceAccount(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) {
+	if instance.Spec.ServiceAccount == nil {
+		return
+	}
+	podSpec.ServiceAccountName = serviceAccountName(instance.Name)
+}
+
+// notebooksForRoleTemplate maps a ClusterRole to the notebooks that use it as
+// their role template, so their Roles follow its rules.
+func notebooksForRoleTemplate(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		notebooks := &v1alpha1.NotebookList{}
+		if err := c.List(context.TODO(), &client.ListOptions{}, notebooks); err != nil {
+			log.Error(err, "unable to list the notebooks of a role template", "name", a.Meta.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, nb := range notebooks.Items {
+			sa := nb.Spec.ServiceAccount
+			if sa == nil || sa.RoleTemplate != a.Meta.GetName() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: nb.Name, Namespace: nb.Namespace},
+			})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestRoleTemplate(name string, template bool, rules ...rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	cr := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}, Rules: rules}
+	if template {
+		cr.Labels = map[string]string{v1alpha1.RoleTemplateLabel: "true"}
+	}
+	return cr
+}
+
+func TestReconcileServiceAccount(t *testing.T) {
+	readPods := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}
+	nb := newTestNotebook()
+	nb.Spec.ServiceAccount = &v1alpha1.ServiceAccountSpec{RoleTemplate: "notebook-viewer"}
+	c := fake.NewFakeClient(nb, newTestRoleTemplate("notebook-viewer", true, readPods))
+	r := newTestReconciler(c)
+
+	nb, ss := reconcileNotebook(t, r)
+	key := types.NamespacedName{Name: "notebook-nb", Namespace: testKey.Namespace}
+	if got := ss.Spec.Template.Spec.ServiceAccountName; got != key.Name {
+		t.Errorf("serviceAccountName = %q; want %q", got, key.Name)
+	}
+	sa := &corev1.ServiceAccount{}
+	if err := c.Get(context.TODO(), key, sa); err != nil {
+		t.Fatalf("Get ServiceAccount: %v", err)
+	}
+	if !metav1.IsControlledBy(sa, nb) {
+		t.Errorf("ServiceAccount owners = %v; want the notebook", sa.OwnerReferences)
+	}
+	role := &rbacv1.Role{}
+	if err := c.Get(context.TODO(), key, role); err != nil {
+		t.Fatalf("Get Role: %v", err)
+	}
+	if want := []rbacv1.PolicyRule{readPods}; !reflect.DeepEqual(role.Rules, want) {
+		t.Errorf("Role rules = %v; want %v", role.Rules, want)
+	}
+	binding := &rbacv1.RoleBinding{}
+	if err := c.Get(context.TODO(), key, binding); err != nil {
+		t.Fatalf("Get RoleBinding: %v", err)
+	}
+	if binding.RoleRef.Name != key.Name || len(binding.Subjects) != 1 || binding.Subjects[0].Name != key.Name {
+		t.Errorf("RoleBinding = %+v; want it to bind the Role to the ServiceAccount", binding)
+	}
+
+	// The Role follows the rules of the template.
+	cr := newTestRoleTemplate("notebook-viewer", true)
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: cr.Name}, cr); err != nil {
+		t.Fatal(err)
+	}
+	cr.Rules = append(cr.Rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}})
+	if err := c.Update(context.TODO(), cr); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNotebook(t, r)
+	if err := c.Get(context.TODO(), key, role); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(role.Rules, cr.Rules) {
+		t.Errorf("Role rules after the template changed = %v; want %v", role.Rules, cr.Rules)
+	}
+
+	// Everything is deleted when the notebook no longer asks for a ServiceAccount.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) { nb.Spec.ServiceAccount = nil })
+	_, ss = reconcileNotebook(t, r)
+	if got := ss.Spec.Template.Spec.ServiceAccountName; got != "" {
+		t.Errorf("serviceAccountName without spec.serviceAccount = %q; want none", got)
+	}
+	if err := c.Get(context.TODO(), key, &corev1.ServiceAccount{}); !errors.IsNotFound(err) {
+		t.Errorf("Get ServiceAccount: err = %v; want NotFound", err)
+	}
+	if err := c.Get(context.TODO(), key, &rbacv1.RoleBinding{}); !errors.IsNotFound(err) {
+		t.Errorf("Get RoleBinding: err = %v; want NotFound", err)
+	}
+}
+
+func TestReconcileServiceAccountInvalidTemplate(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.ServiceAccount = &v1alpha1.ServiceAccountSpec{RoleTemplate: "cluster-admin"}
+	c := fake.NewFakeClient(nb, newTestRoleTemplate("cluster-admin", false, rbacv1.PolicyRule{
+		APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"},
+	}))
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+
+	reconcileNotebook(t, r)
+	key := types.NamespacedName{Name: "notebook-nb", Namespace: testKey.Namespace}
+	if err := c.Get(context.TODO(), key, &corev1.ServiceAccount{}); err != nil {
+		t.Errorf("Get ServiceAccount: %v", err)
+	}
+	if err := c.Get(context.TODO(), key, &rbacv1.Role{}); !errors.IsNotFound(err) {
+		t.Errorf("Get Role: err = %v; want NotFound", err)
+	}
+	want := "Warning InvalidSpec ClusterRole cluster-admin is not a role template: it lacks the label notebooks.kubeflow.org/role-template=true"
+	if !hasEvent(recorder, want) {
+		t.Errorf("missing event %q", want)
+	}
+}
+
+func TestReconcileServiceAccountNotOwned(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.ServiceAccount = &v1alpha1.ServiceAccountSpec{}
+	existing := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "notebook-nb", Namespace: testKey.Namespace}}
+	r := newTestReconciler(fake.NewFakeClient(nb, existing))
+
+	if err := r.ReconcileServiceAccount(nb); err == nil {
+		t.Error("ReconcileServiceAccount with a ServiceAccount of another owner succeeded; want an error")
+	}
+}
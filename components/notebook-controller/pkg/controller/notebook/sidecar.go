@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/sidecar"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reasonUnknownSidecar is the reason of the event recorded on notebooks that
+// select sidecars that aren't defined.
+const reasonUnknownSidecar = "UnknownSidecar"
+
+// injectSidecars adds the sidecars selected for the notebook to podSpec.
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+func (r *ReconcileNotebook) injectSidecars(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) error {
+	if r.sidecars == nil {
+		return nil
+	}
+	unknown, err := r.sidecars.Inject(context.TODO(), instance, podSpec)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, reasonUnknownSidecar,
+			"Sidecars %v are not defined", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// notebooksForSidecarConfig maps the ConfigMap of the sidecar definitions to all
+// notebooks, so the changes of the definitions are rolled out. The invalid
+// definitions are logged, as they only fail the notebooks that select them.
+func notebooksForSidecarConfig(c client.Client, configMap string) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		if a.Meta.GetNamespace()+"/"+a.Meta.GetName() != configMap {
+			return nil
+		}
+		if cm, ok := a.Object.(*corev1.ConfigMap); ok {
+			_, invalid := sidecar.Parse(cm)
+			for name, err := range invalid {
+				log.Error(err, "ignoring invalid sidecar", "configmap", configMap, "sidecar", name)
+			}
+		}
+		return notebookRequests(c, "")
+	}
+}
+
+// notebooksForNamespace maps a namespace to its notebooks, so the sidecars
+// selected by its labels are injected.
+func notebooksForNamespace(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		return notebookRequests(c, a.Meta.GetName())
+	}
+}
+
+// notebookRequests returns the requests for the notebooks of the namespace, or
+// of all namespaces if it is empty.
+func notebookRequests(c client.Client, namespace string) []reconcile.Request {
+	notebooks := &v1alpha1.NotebookList{}
+	if err := c.List(context.TODO(), client.InNamespace(namespace), notebooks); err != nil {
+		log.Error(err, "unable to list notebooks", "namespace", namespace)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, nb := range notebooks.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: nb.Name, Namespace: nb.Namespace},
+		})
+	}
+	return requests
+}
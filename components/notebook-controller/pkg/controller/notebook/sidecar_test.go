@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/sidecar"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileSidecars(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "notebook-sidecars", Namespace: "kubeflow"},
+		Data: map[string]string{
+			"git-sync":    "containers:\n- name: git-sync\n  image: k8s.gcr.io/git-sync:v3.1.1\n",
+			"log-shipper": "order: -1\ncontainers:\n- name: fluent-bit\n  image: fluent/fluent-bit:1.0\n",
+		},
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   testKey.Namespace,
+			Labels: map[string]string{sidecar.NamespaceLabelPrefix + "log-shipper": "enabled"},
+		},
+	}
+	nb := newTestNotebook()
+	nb.Annotations = map[string]string{sidecar.Annotation: "git-sync,vscode"}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, nb, cm, ns)
+	r := newTestReconciler(c)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	r.sidecars = &sidecar.Injector{
+		Client:    c,
+		ConfigMap: types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace},
+	}
+
+	_, ss := reconcileNotebook(t, r)
+	var containers []string
+	for _, c := range ss.Spec.Template.Spec.Containers {
+		containers = append(containers, c.Name)
+	}
+	want := []string{"nb", "fluent-bit", "git-sync"}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("containers of the StatefulSet = %v; want %v", containers, want)
+	}
+	if got := <-recorder.Events; got != "Warning UnknownSidecar Sidecars vscode are not defined" {
+		t.Errorf("event = %q; want the unknown sidecar", got)
+	}
+
+	// The injection is stable, so the StatefulSet isn't updated again.
+	reconcileNotebook(t, r)
+	for len(recorder.Events) > 0 {
+		if got := <-recorder.Events; got == "Normal Updated Updated StatefulSet nb to match the notebook spec" {
+			t.Errorf("StatefulSet updated by the second reconcile")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ReconcileStatus fills in the status of the notebook running podSpec from its
+// StatefulSet and Pod.
+// The status itself is written by Reconcile.
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+func (r *ReconcileNotebook) ReconcileStatus(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) error {
+	ss := &appsv1.StatefulSet{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, ss)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	instance.Status.ReadyReplicas = ss.Status.ReadyReplicas
+	setCondition(&instance.Status, stoppedCondition(instance, ss))
+
+	// The StatefulSet runs a single Pod.
+	pod := &corev1.Pod{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: instance.Name + "-0", Namespace: instance.Namespace}, pod)
+	if err != nil && errors.IsNotFound(err) {
+		instance.Status.ContainerState = corev1.ContainerState{}
+		cond := v1alpha1.NotebookCondition{
+			Type:    v1alpha1.NotebookReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "PodNotFound",
+			Message: "The notebook Pod does not exist",
+		}
+		if isStopped(instance) {
+			cond.Reason = "Stopped"
+			cond.Message = "The notebook is stopped"
+		}
+		setCondition(&instance.Status, cond)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	instance.Status.ContainerState = corev1.ContainerState{}
+	cs := notebookContainerStatus(podSpec, pod)
+	if cs != nil {
+		instance.Status.ContainerState = cs.State
+	}
+	setCondition(&instance.Status, readyCondition(pod, cs))
+	return nil
+}
+
+// stoppedCondition derives the Stopped condition of the notebook from its spec,
+// its schedule and the number of Pods of its StatefulSet.
+func stoppedCondition(instance *v1alpha1.Notebook, ss *appsv1.StatefulSet) v1alpha1.NotebookCondition {
+	cond := v1alpha1.NotebookCondition{
+		Type:   v1alpha1.NotebookStopped,
+		Status: corev1.ConditionFalse,
+		Reason: "Running",
+	}
+	switch {
+	case !isStopped(instance):
+	case ss.Status.Replicas > 0:
+		cond.Reason = "Stopping"
+		cond.Message = "Waiting for the notebook Pod to terminate"
+	default:
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = "Stopped"
+	}
+	if cond.Status == corev1.ConditionTrue && !instance.Spec.Stopped {
+		cond.Reason = "OutsideSchedule"
+		cond.Message = "Stopped by the schedule until " + instance.Status.Schedule.NextTransition.UTC().Format(time.RFC3339)
+	}
+	return cond
+}
+
+// notebookContainerStatus returns the status of the notebook container, which is
+// the first container of the notebook's PodSpec.
+func notebookContainerStatus(podSpec *corev1.PodSpec, pod *corev1.Pod) *corev1.ContainerStatus {
+	name := ""
+	if containers := podSpec.Containers; len(containers) > 0 {
+		name = containers[0].Name
+	}
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == name {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+// readyCondition derives the Ready condition of the notebook from its Pod. If the
+// Pod isn't ready, the status of the notebook container, if known, explains why.
+func readyCondition(pod *corev1.Pod, cs *corev1.ContainerStatus) v1alpha1.NotebookCondition {
+	cond := v1alpha1.NotebookCondition{
+		Type:   v1alpha1.NotebookReady,
+		Status: corev1.ConditionUnknown,
+		Reason: string(pod.Status.Phase),
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			cond.Status = c.Status
+			cond.Reason = c.Reason
+			cond.Message = c.Message
+		}
+	}
+	if cond.Status == corev1.ConditionTrue {
+		cond.Reason = "Running"
+		cond.Message = ""
+		return cond
+	}
+	if cs == nil {
+		cs = &corev1.ContainerStatus{}
+	}
+	state := cs.State
+	switch {
+	case state.Waiting != nil:
+		cond.Reason = state.Waiting.Reason
+		cond.Message = state.Waiting.Message
+	case state.Terminated != nil:
+		cond.Reason = state.Terminated.Reason
+		cond.Message = state.Terminated.Message
+	case state.Running != nil && !cs.Ready:
+		// The readiness probe, by default of the Jupyter API, hasn't succeeded.
+		cond.Reason = "ServerNotReady"
+		cond.Message = "The notebook container is running, but its readiness probe hasn't succeeded"
+	}
+	if cond.Reason == "" {
+		cond.Reason = string(pod.Status.Phase)
+	}
+	return cond
+}
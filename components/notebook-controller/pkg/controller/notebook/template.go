@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// notebookPodSpec returns the PodSpec of the notebook, with its template merged in.
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooktemplates;clusternotebooktemplates,verbs=get;list;watch
+func (r *ReconcileNotebook) notebookPodSpec(instance *v1alpha1.Notebook) (*corev1.PodSpec, error) {
+	return podspec.Resolve(context.TODO(), r.Client, instance)
+}
+
+// notebooksForTemplate maps a template of the given kind to the notebooks
+// created from it, so they are reconciled when it changes.
+func notebooksForTemplate(c client.Client, kind string) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		notebooks := &v1alpha1.NotebookList{}
+		// A ClusterNotebookTemplate has no namespace, so all notebooks are listed.
+		opts := client.InNamespace(a.Meta.GetNamespace())
+		if err := c.List(context.TODO(), opts, notebooks); err != nil {
+			log.Error(err, "unable to list the notebooks of a template", "kind", kind, "name", a.Meta.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, nb := range notebooks.Items {
+			ref := nb.Spec.TemplateRef
+			if ref == nil || podspec.TemplateKind(ref) != kind || ref.Name != a.Meta.GetName() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: nb.Name, Namespace: nb.Namespace},
+			})
+		}
+		return requests
+	}
+}
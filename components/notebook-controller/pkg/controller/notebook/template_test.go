@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestIntegrationTemplate(t *testing.T) {
+	const namespace = "integration-template"
+	nb := newIntegrationNotebook(namespace)
+	nb.Spec.TemplateRef = &v1alpha1.TemplateReference{Name: "tensorflow"}
+	nb.Spec.Template.Spec.Containers[0].Image = ""
+	nb.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "USER", Value: "alice"}}
+	key := types.NamespacedName{Namespace: nb.Namespace, Name: nb.Name}
+	if err := testClient.Create(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Delete(context.TODO(), nb)
+
+	// The notebook is created once its template exists.
+	template := &v1alpha1.NotebookTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "tensorflow", Namespace: namespace},
+		Spec: v1alpha1.NotebookTemplateDefinition{
+			Template: v1alpha1.NotebookTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "nb", Image: "tensorflow:1.12"}},
+			}},
+		},
+	}
+	if err := testClient.Create(context.TODO(), template); err != nil {
+		t.Fatal(err)
+	}
+	ss := &appsv1.StatefulSet{}
+	eventually(t, func() error { return getOwned(key, ss, nb) })
+	c := ss.Spec.Template.Spec.Containers[0]
+	if c.Image != "tensorflow:1.12" || len(c.Env) != 1 || c.WorkingDir != v1alpha1.DefaultWorkingDir {
+		t.Errorf("notebook container = %+v; want the image of the template, the env of the notebook and the defaults", c)
+	}
+
+	// A new image in the template is rolled out to the notebook.
+	template.Spec.Template.Spec.Containers[0].Image = "tensorflow:1.13"
+	if err := testClient.Update(context.TODO(), template); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error {
+		if err := getOwned(key, ss, nb); err != nil {
+			return err
+		}
+		if image := ss.Spec.Template.Spec.Containers[0].Image; image != "tensorflow:1.13" {
+			return fmt.Errorf("image of the StatefulSet = %v; want tensorflow:1.13", image)
+		}
+		return nil
+	})
+}
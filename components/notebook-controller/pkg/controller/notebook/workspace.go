@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// workspaceVolumeName is the name of the workspace volume in the notebook Pod.
+const workspaceVolumeName = "workspace"
+
+// ReconcileWorkspace reconciles the PersistentVolumeClaim of the notebook workspace,
+// and returns whether the claim exists. The claim is only owned by the notebook,
+// and thus garbage collected with it, if the retain policy is Delete. The
+// workspace of a notebook cloned from a snapshot is restored from the
+// VolumeSnapshot of the snapshot.
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+func (r *ReconcileNotebook) ReconcileWorkspace(instance *v1alpha1.Notebook) (bool, error) {
+	ws := instance.Spec.Workspace
+	if ws == nil {
+		return true, nil
+	}
+
+	accessMode := ws.AccessMode
+	if accessMode == "" {
+		accessMode = corev1.ReadWriteOnce
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.WorkspaceClaimName(instance.Name),
+			Namespace: instance.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: ws.Size},
+			},
+		},
+	}
+	if ws.StorageClassName != "" {
+		storageClass := ws.StorageClassName
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	if ws.RetainPolicy == v1alpha1.WorkspaceDelete {
+		if err := controllerutil.SetControllerReference(instance, pvc, r.scheme); err != nil {
+			return false, err
+		}
+	}
+
+	// Check if the PersistentVolumeClaim already exists
+	found := &corev1.PersistentVolumeClaim{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: pvc.Name, Namespace: pvc.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		if ws.StorageClassName == "" {
+			sClasses := &storagev1.StorageClassList{}
+			if err := r.List(context.TODO(), &client.ListOptions{}, sClasses); err != nil {
+				return false, err
+			}
+			if !util.HasDefaultStorage(sClasses) {
+				setCondition(&instance.Status, v1alpha1.NotebookCondition{
+					Type:    v1alpha1.NotebookWorkspaceReady,
+					Status:  corev1.ConditionFalse,
+					Reason:  "NoDefaultStorageClass",
+					Message: "The workspace has no storage class and the cluster has no default storage class",
+				})
+				return false, nil
+			}
+		}
+		// The data source of a claim can only be set when it is created.
+		if pvc.Spec.DataSource, err = r.workspaceDataSource(instance); err != nil {
+			return false, err
+		}
+		log.Info("Creating PersistentVolumeClaim", "namespace", pvc.Namespace, "name", pvc.Name)
+		if err := r.Create(context.TODO(), pvc); err != nil {
+			return false, err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created PersistentVolumeClaim %v", pvc.Name)
+		found = pvc
+	} else if err != nil {
+		return false, err
+	}
+
+	// Most of the claim spec is immutable. Only the requested size, for volume
+	// expansion, and the ownership, for the retain policy, are updated.
+	requireUpdate := false
+	if ws.Size.Cmp(found.Spec.Resources.Requests[corev1.ResourceStorage]) > 0 {
+		if found.Spec.Resources.Requests == nil {
+			found.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		found.Spec.Resources.Requests[corev1.ResourceStorage] = ws.Size
+		requireUpdate = true
+	}
+	if metav1.IsControlledBy(found, instance) != (ws.RetainPolicy == v1alpha1.WorkspaceDelete) {
+		found.OwnerReferences = pvc.OwnerReferences
+		requireUpdate = true
+	}
+	if requireUpdate {
+		log.Info("Updating PersistentVolumeClaim", "namespace", pvc.Namespace, "name", pvc.Name)
+		if err := r.Update(context.TODO(), found); err != nil {
+			return true, err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonUpdated, "Updated PersistentVolumeClaim %v to match the notebook workspace", pvc.Name)
+	}
+
+	cond := v1alpha1.NotebookCondition{
+		Type:   v1alpha1.NotebookWorkspaceReady,
+		Status: corev1.ConditionFalse,
+		Reason: string(found.Status.Phase),
+	}
+	if found.Status.Phase == corev1.ClaimBound {
+		cond.Status = corev1.ConditionTrue
+	}
+	if cond.Reason == "" {
+		cond.Reason = string(corev1.ClaimPending)
+	}
+	setCondition(&instance.Status, cond)
+	return true, nil
+}
+
+// notebookForWorkspaceClaim maps a PersistentVolumeClaim to the notebook whose
+// workspace it may be. Claims that are kept when their notebook is deleted are
+// not owned by it, so they are mapped by name.
+func notebookForWorkspaceClaim(a handler.MapObject) []reconcile.Request {
+	prefix := util.WorkspaceClaimName("")
+	name := a.Meta.GetName()
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: strings.TrimPrefix(name, prefix), Namespace: a.Meta.GetNamespace()}},
+	}
+}
+
+// addWorkspaceVolume mounts the workspace claim into the notebook container.
+func addWorkspaceVolume(instance *v1alpha1.Notebook, podSpec *corev1.PodSpec) {
+	ws := instance.Spec.Workspace
+	if ws == nil {
+		return
+	}
+	mountPath := ws.MountPath
+	if mountPath == "" {
+		mountPath = v1alpha1.DefaultWorkingDir
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: workspaceVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: util.WorkspaceClaimName(instance.Name),
+			},
+		},
+	})
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      workspaceVolumeName,
+		MountPath: mountPath,
+	})
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var claimKey = types.NamespacedName{Name: util.WorkspaceClaimName(testKey.Name), Namespace: testKey.Namespace}
+
+func getClaim(t *testing.T, r *ReconcileNotebook) *corev1.PersistentVolumeClaim {
+	t.Helper()
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(context.TODO(), claimKey, pvc); err != nil {
+		t.Fatal(err)
+	}
+	return pvc
+}
+
+func TestReconcileWorkspace(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Workspace = &v1alpha1.WorkspaceSpec{
+		Size:             resource.MustParse("10Gi"),
+		StorageClassName: "standard",
+		MountPath:        "/data",
+		RetainPolicy:     v1alpha1.WorkspaceDelete,
+	}
+	c := fake.NewFakeClient(nb)
+	r := newTestReconciler(c)
+
+	nb, ss := reconcileNotebook(t, r)
+	pvc := getClaim(t, r)
+	wantSpec := corev1.PersistentVolumeClaimSpec{
+		AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		StorageClassName: &nb.Spec.Workspace.StorageClassName,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+	if !reflect.DeepEqual(pvc.Spec, wantSpec) {
+		t.Errorf("claim spec = %+v; want %+v", pvc.Spec, wantSpec)
+	}
+	if !metav1.IsControlledBy(pvc, nb) {
+		t.Errorf("claim with retain policy Delete not owned by the notebook")
+	}
+	podSpec := ss.Spec.Template.Spec
+	wantVolume := corev1.Volume{
+		Name: workspaceVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimKey.Name},
+		},
+	}
+	if len(podSpec.Volumes) != 1 || !reflect.DeepEqual(podSpec.Volumes[0], wantVolume) {
+		t.Errorf("volumes = %+v; want the workspace claim", podSpec.Volumes)
+	}
+	wantMount := corev1.VolumeMount{Name: workspaceVolumeName, MountPath: "/data"}
+	if mounts := podSpec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0] != wantMount {
+		t.Errorf("volume mounts = %+v; want %+v", mounts, wantMount)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookWorkspaceReady, corev1.ConditionFalse, string(corev1.ClaimPending))
+
+	pvc.Status.Phase = corev1.ClaimBound
+	if err := c.Status().Update(context.TODO(), pvc); err != nil {
+		t.Fatal(err)
+	}
+	nb, _ = reconcileNotebook(t, r)
+	checkCondition(t, nb, v1alpha1.NotebookWorkspaceReady, corev1.ConditionTrue, string(corev1.ClaimBound))
+
+	// The claim is expanded, and kept when the notebook is deleted.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+		nb.Spec.Workspace.Size = resource.MustParse("20Gi")
+		nb.Spec.Workspace.RetainPolicy = v1alpha1.WorkspaceRetain
+	})
+	nb, _ = reconcileNotebook(t, r)
+	pvc = getClaim(t, r)
+	if size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; size.Cmp(resource.MustParse("20Gi")) != 0 {
+		t.Errorf("requested size = %v; want 20Gi", size.String())
+	}
+	if metav1.IsControlledBy(pvc, nb) {
+		t.Errorf("claim with retain policy Retain owned by the notebook")
+	}
+
+	// Claims are not shrunk.
+	updateNotebook(t, c, func(nb *v1alpha1.Notebook) {
+		nb.Spec.Workspace.Size = resource.MustParse("5Gi")
+	})
+	reconcileNotebook(t, r)
+	pvc = getClaim(t, r)
+	if size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; size.Cmp(resource.MustParse("20Gi")) != 0 {
+		t.Errorf("requested size after shrinking = %v; want 20Gi", size.String())
+	}
+}
+
+func TestReconcileWorkspaceNoDefaultStorageClass(t *testing.T) {
+	nb := newTestNotebook()
+	nb.Spec.Workspace = &v1alpha1.WorkspaceSpec{Size: resource.MustParse("10Gi")}
+	standard := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "standard"},
+		Provisioner: "kubernetes.io/gce-pd",
+	}
+	c := fake.NewFakeClient(nb, standard)
+	r := newTestReconciler(c)
+
+	// Without a claim, the Pod would stay pending, so nothing is started.
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: testKey}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := r.Get(context.TODO(), testKey, &appsv1.StatefulSet{}); !errors.IsNotFound(err) {
+		t.Errorf("StatefulSet of a notebook without a workspace claim: %v; want NotFound", err)
+	}
+	if err := r.Get(context.TODO(), claimKey, &corev1.PersistentVolumeClaim{}); !errors.IsNotFound(err) {
+		t.Errorf("claim without a storage class: %v; want NotFound", err)
+	}
+	nb = &v1alpha1.Notebook{}
+	if err := r.Get(context.TODO(), testKey, nb); err != nil {
+		t.Fatal(err)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookWorkspaceReady, corev1.ConditionFalse, "NoDefaultStorageClass")
+
+	standard.Annotations = map[string]string{util.DefaultStorageAnnotations[0]: "true"}
+	if err := c.Update(context.TODO(), standard); err != nil {
+		t.Fatal(err)
+	}
+	nb, ss := reconcileNotebook(t, r)
+	if pvc := getClaim(t, r); pvc.Spec.StorageClassName != nil {
+		t.Errorf("storage class of the claim = %v; want the default", *pvc.Spec.StorageClassName)
+	}
+	if len(ss.Spec.Template.Spec.Volumes) != 1 {
+		t.Errorf("volumes = %+v; want the workspace claim", ss.Spec.Template.Spec.Volumes)
+	}
+	checkCondition(t, nb, v1alpha1.NotebookWorkspaceReady, corev1.ConditionFalse, string(corev1.ClaimPending))
+}
+
+func TestNotebookForWorkspaceClaim(t *testing.T) {
+	tests := []struct {
+		claim string
+		want  []reconcile.Request
+	}{
+		{claim: "workspace-nb", want: []reconcile.Request{{NamespacedName: testKey}}},
+		{claim: "workspace-"},
+		{claim: "data-nb"},
+	}
+	for _, test := range tests {
+		meta := &metav1.ObjectMeta{Name: test.claim, Namespace: testKey.Namespace}
+		got := notebookForWorkspaceClaim(handler.MapObject{Meta: meta})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("requests for claim %q = %v; want %v", test.claim, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebooksnapshot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/health"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/shutdown"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("notebooksnapshot-controller")
+
+// The reasons of the events recorded on snapshots.
+const (
+	reasonCaptured = "Captured"
+	reasonCreated  = "Created"
+	reasonFailed   = "ReconcileFailed"
+)
+
+// readyCheckPeriod is how often a snapshot that isn't ready to use is checked
+// again. VolumeSnapshots aren't watched, as their CRD is optional.
+const readyCheckPeriod = 10 * time.Second
+
+// Add creates a new NotebookSnapshot Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileNotebookSnapshot{
+		Client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetRecorder("notebooksnapshot-controller"),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("notebooksnapshot-controller", mgr, controller.Options{Reconciler: shutdown.Track(health.Track(r))})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to NotebookSnapshot
+	err = c.Watch(&source.Kind{Type: &v1alpha1.NotebookSnapshot{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcileNotebookSnapshot{}
+
+// ReconcileNotebookSnapshot reconciles a NotebookSnapshot object
+type ReconcileNotebookSnapshot struct {
+	client.Client
+	scheme *runtime.Scheme
+	// recorder emits the events of the snapshots.
+	recorder record.EventRecorder
+}
+
+// Reconcile captures the notebook of a NotebookSnapshot once, and then tracks
+// whether the VolumeSnapshot of its workspace is ready to use.
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooksnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooksnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+func (r *ReconcileNotebookSnapshot) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// Fetch the NotebookSnapshot instance
+	instance := &v1alpha1.NotebookSnapshot{}
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+	status := instance.Status.DeepCopy()
+
+	if instance.Status.NotebookSpec == nil {
+		if err = r.capture(instance); err != nil {
+			return r.reconcileFailed(instance, err)
+		}
+	}
+	if instance.Status.NotebookSpec != nil {
+		if err = r.reconcileReadiness(instance); err != nil {
+			return r.reconcileFailed(instance, err)
+		}
+	}
+	if !reflect.DeepEqual(status, &instance.Status) {
+		log.Info("Updating NotebookSnapshot status", "namespace", instance.Namespace, "name", instance.Name)
+		if err = r.Status().Update(context.TODO(), instance); err != nil {
+			return r.reconcileFailed(instance, err)
+		}
+	}
+	if !instance.Status.ReadyToUse {
+		return reconcile.Result{RequeueAfter: readyCheckPeriod}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// reconcileFailed records a warning event for err on the snapshot and returns
+// the error to requeue it. Conflicts are retried without an event.
+func (r *ReconcileNotebookSnapshot) reconcileFailed(instance *v1alpha1.NotebookSnapshot, err error) (reconcile.Result, error) {
+	if !errors.IsConflict(err) {
+		r.recorder.Event(instance, corev1.EventTypeWarning, reasonFailed, err.Error())
+	}
+	return reconcile.Result{}, err
+}
+
+// capture records the spec of the notebook in the status of the snapshot, and
+// creates the VolumeSnapshot of its workspace. If the notebook can't be
+// captured yet, the status says why.
+func (r *ReconcileNotebookSnapshot) capture(instance *v1alpha1.NotebookSnapshot) error {
+	nb := &v1alpha1.Notebook{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.NotebookName, Namespace: instance.Namespace}, nb)
+	if errors.IsNotFound(err) {
+		instance.Status.Message = fmt.Sprintf("Notebook %v not found", instance.Spec.NotebookName)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// The PodSpec is captured with the template of the notebook merged in, so
+	// clones don't change with the template.
+	podSpec, err := podspec.Resolve(context.TODO(), r.Client, nb)
+	if podspec.IsPending(err) {
+		instance.Status.Message = err.Error()
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if nb.Spec.Workspace != nil {
+		vs, err := r.createVolumeSnapshot(instance)
+		if err != nil {
+			return err
+		}
+		instance.Status.VolumeSnapshotName = vs.GetName()
+	}
+
+	spec := nb.Spec.DeepCopy()
+	spec.Template.Spec = *podSpec.DeepCopy()
+	spec.TemplateRef = nil
+	spec.CloneFrom = ""
+	spec.Stopped = false
+	now := metav1.Now()
+	instance.Status.NotebookSpec = spec
+	instance.Status.CaptureTime = &now
+	instance.Status.Message = ""
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCaptured, "Captured notebook %v", nb.Name)
+	return nil
+}
+
+// createVolumeSnapshot creates the VolumeSnapshot of the workspace of the
+// notebook. It is owned by the snapshot and has the same name.
+func (r *ReconcileNotebookSnapshot) createVolumeSnapshot(instance *v1alpha1.NotebookSnapshot) (*unstructured.Unstructured, error) {
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(util.VolumeSnapshotGroupVersionKind)
+	vs.SetName(instance.Name)
+	vs.SetNamespace(instance.Namespace)
+	source := map[string]interface{}{
+		"kind": "PersistentVolumeClaim",
+		"name": util.WorkspaceClaimName(instance.Spec.NotebookName),
+	}
+	if err := unstructured.SetNestedMap(vs.Object, source, "spec", "source"); err != nil {
+		return nil, err
+	}
+	if class := instance.Spec.VolumeSnapshotClassName; class != "" {
+		if err := unstructured.SetNestedField(vs.Object, class, "spec", "snapshotClassName"); err != nil {
+			return nil, err
+		}
+	}
+	if err := controllerutil.SetControllerReference(instance, vs, r.scheme); err != nil {
+		return nil, err
+	}
+
+	log.Info("Creating VolumeSnapshot", "namespace", vs.GetNamespace(), "name", vs.GetName())
+	err := r.Create(context.TODO(), vs)
+	if errors.IsAlreadyExists(err) {
+		// Created by a previous capture whose status update failed.
+		return vs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonCreated, "Created VolumeSnapshot %v", vs.GetName())
+	return vs, nil
+}
+
+// reconcileReadiness sets whether the captured snapshot is ready to use, that
+// is whether the VolumeSnapshot of the workspace is, if the notebook had one.
+func (r *ReconcileNotebookSnapshot) reconcileReadiness(instance *v1alpha1.NotebookSnapshot) error {
+	name := instance.Status.VolumeSnapshotName
+	if name == "" {
+		instance.Status.ReadyToUse = true
+		instance.Status.Message = ""
+		return nil
+	}
+
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(util.VolumeSnapshotGroupVersionKind)
+	err := r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: instance.Namespace}, vs)
+	if errors.IsNotFound(err) {
+		instance.Status.ReadyToUse = false
+		instance.Status.Message = fmt.Sprintf("VolumeSnapshot %v not found", name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ready, _, _ := unstructured.NestedBool(vs.Object, "status", "readyToUse")
+	message, _, _ := unstructured.NestedString(vs.Object, "status", "error", "message")
+	instance.Status.ReadyToUse = ready
+	instance.Status.Message = message
+	return nil
+}
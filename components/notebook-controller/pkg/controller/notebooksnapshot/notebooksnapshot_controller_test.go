@@ -0,0 +1,191 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notebooksnapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func init() {
+	// The fake client decodes objects with the client-go scheme.
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+var testKey = types.NamespacedName{Name: "before-experiment", Namespace: "test"}
+
+func newTestNotebook(workspace bool) *v1alpha1.Notebook {
+	nb := &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: testKey.Namespace},
+		Spec: v1alpha1.NotebookSpec{
+			Template: v1alpha1.NotebookTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "nb", Image: "jupyter:1"}},
+			}},
+			Stopped: true,
+		},
+	}
+	if workspace {
+		nb.Spec.Workspace = &v1alpha1.WorkspaceSpec{Size: resource.MustParse("10Gi")}
+	}
+	return nb
+}
+
+func newTestSnapshot() *v1alpha1.NotebookSnapshot {
+	return &v1alpha1.NotebookSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: testKey.Name, Namespace: testKey.Namespace},
+		Spec: v1alpha1.NotebookSnapshotSpec{
+			NotebookName:            "nb",
+			VolumeSnapshotClassName: "csi-snapclass",
+		},
+	}
+}
+
+// reconcileSnapshot runs a reconcile and returns its result and the resulting snapshot.
+func reconcileSnapshot(t *testing.T, r *ReconcileNotebookSnapshot) (reconcile.Result, *v1alpha1.NotebookSnapshot) {
+	t.Helper()
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: testKey})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	snapshot := &v1alpha1.NotebookSnapshot{}
+	if err := r.Get(context.TODO(), testKey, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return result, snapshot
+}
+
+func newTestReconciler(objs ...runtime.Object) *ReconcileNotebookSnapshot {
+	return &ReconcileNotebookSnapshot{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(100),
+	}
+}
+
+func TestReconcileCapture(t *testing.T) {
+	r := newTestReconciler(newTestNotebook(true), newTestSnapshot())
+	result, snapshot := reconcileSnapshot(t, r)
+
+	spec := snapshot.Status.NotebookSpec
+	if spec == nil || spec.Template.Spec.Containers[0].Image != "jupyter:1" || spec.Workspace == nil {
+		t.Fatalf("captured spec = %+v; want the spec of the notebook", spec)
+	}
+	if spec.Stopped {
+		t.Errorf("captured spec is stopped; want clones to run")
+	}
+	if snapshot.Status.VolumeSnapshotName != testKey.Name || snapshot.Status.ReadyToUse || result.RequeueAfter == 0 {
+		t.Errorf("status = %+v, result = %+v; want a VolumeSnapshot that isn't ready, checked again", snapshot.Status, result)
+	}
+
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(util.VolumeSnapshotGroupVersionKind)
+	if err := r.Get(context.TODO(), testKey, vs); err != nil {
+		t.Fatal(err)
+	}
+	claim, _, _ := unstructured.NestedString(vs.Object, "spec", "source", "name")
+	class, _, _ := unstructured.NestedString(vs.Object, "spec", "snapshotClassName")
+	if claim != "workspace-nb" || class != "csi-snapclass" {
+		t.Errorf("VolumeSnapshot of claim %q with class %q; want workspace-nb and csi-snapclass", claim, class)
+	}
+	if ref := metav1.GetControllerOf(vs); ref == nil || ref.Name != testKey.Name {
+		t.Errorf("VolumeSnapshot is controlled by %+v; want the snapshot", ref)
+	}
+
+	// The error of the VolumeSnapshot is reported, and the snapshot is ready
+	// once the VolumeSnapshot is.
+	setVolumeSnapshotStatus(t, r.Client, vs, map[string]interface{}{
+		"readyToUse": false,
+		"error":      map[string]interface{}{"message": "snapshot controller failed"},
+	})
+	_, snapshot = reconcileSnapshot(t, r)
+	if snapshot.Status.ReadyToUse || snapshot.Status.Message != "snapshot controller failed" {
+		t.Errorf("status = %+v; want the error of the VolumeSnapshot", snapshot.Status)
+	}
+	setVolumeSnapshotStatus(t, r.Client, vs, map[string]interface{}{"readyToUse": true})
+	result, snapshot = reconcileSnapshot(t, r)
+	if !snapshot.Status.ReadyToUse || snapshot.Status.Message != "" || result.RequeueAfter != 0 {
+		t.Errorf("status = %+v, result = %+v; want ready", snapshot.Status, result)
+	}
+
+	// Later changes to the notebook aren't captured.
+	nb := &v1alpha1.Notebook{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: "nb", Namespace: testKey.Namespace}, nb); err != nil {
+		t.Fatal(err)
+	}
+	nb.Spec.Template.Spec.Containers[0].Image = "jupyter:2"
+	if err := r.Update(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	_, snapshot = reconcileSnapshot(t, r)
+	if image := snapshot.Status.NotebookSpec.Template.Spec.Containers[0].Image; image != "jupyter:1" {
+		t.Errorf("captured image = %v; want jupyter:1", image)
+	}
+}
+
+func setVolumeSnapshotStatus(t *testing.T, c client.Client, vs *unstructured.Unstructured, status map[string]interface{}) {
+	t.Helper()
+	if err := c.Get(context.TODO(), testKey, vs); err != nil {
+		t.Fatal(err)
+	}
+	vs.Object["status"] = status
+	if err := c.Status().Update(context.TODO(), vs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileCaptureWithoutWorkspace(t *testing.T) {
+	r := newTestReconciler(newTestNotebook(false), newTestSnapshot())
+	result, snapshot := reconcileSnapshot(t, r)
+	if snapshot.Status.NotebookSpec == nil || snapshot.Status.VolumeSnapshotName != "" ||
+		!snapshot.Status.ReadyToUse || result.RequeueAfter != 0 {
+		t.Errorf("status = %+v, result = %+v; want ready without a VolumeSnapshot", snapshot.Status, result)
+	}
+}
+
+func TestReconcileNotebookNotFound(t *testing.T) {
+	r := newTestReconciler(newTestSnapshot())
+	result, snapshot := reconcileSnapshot(t, r)
+	if snapshot.Status.NotebookSpec != nil || snapshot.Status.Message != "Notebook nb not found" || result.RequeueAfter == 0 {
+		t.Errorf("status = %+v, result = %+v; want the missing notebook, checked again", snapshot.Status, result)
+	}
+
+	// The notebook is captured once it exists.
+	if err := r.Create(context.TODO(), newTestNotebook(false)); err != nil {
+		t.Fatal(err)
+	}
+	_, snapshot = reconcileSnapshot(t, r)
+	if snapshot.Status.NotebookSpec == nil || !snapshot.Status.ReadyToUse {
+		t.Errorf("status = %+v; want the notebook captured", snapshot.Status)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prepull
+
+import (
+	"flag"
+	"strings"
+)
+
+// Options configures the image pre-pull controller.
+type Options struct {
+	// Namespace of the pre-pull DaemonSet. Images are not pre-pulled if it is empty.
+	Namespace string
+	// Images is the comma-separated allow-list of the images to pre-pull. The
+	// images of all notebooks are pre-pulled if it is empty.
+	Images string
+}
+
+// DefaultOptions are the options used by Add. The manager sets them from its
+// command line flags before adding the controller.
+var DefaultOptions = Options{}
+
+// AddFlags registers the controller options with fs.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Namespace, "prepull-namespace", o.Namespace, "The namespace of the DaemonSet that pre-pulls notebook images on all nodes. Images are not pre-pulled if empty.")
+	fs.StringVar(&o.Images, "prepull-images", o.Images, "Comma-separated images to pre-pull. The images of all notebooks if empty.")
+}
+
+// allowList returns the images of the allow-list.
+func (o *Options) allowList() []string {
+	var images []string
+	for _, image := range strings.Split(o.Images, ",") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,444 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prepull manages a DaemonSet that pulls the images of notebooks on
+// all nodes ahead of time, so that notebooks start quickly on any node.
+package prepull
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/health"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/shutdown"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("prepull-controller")
+
+const (
+	// Name is the name of the DaemonSet, and the value of the app label of its Pods.
+	Name = "notebook-image-prepull"
+	// StatusConfigMapName is the name of the ConfigMap that reports which
+	// images are pulled on each node.
+	StatusConfigMapName = "notebook-image-prepull-status"
+	// PauseImage is run by the Pods of the DaemonSet once all images are pulled.
+	PauseImage = "k8s.gcr.io/pause:3.1"
+)
+
+// The pull status of an image on a node. Failed is followed by the reason,
+// e.g. "Failed: ImagePullBackOff".
+const (
+	StatusPulled  = "Pulled"
+	StatusPulling = "Pulling"
+	StatusFailed  = "Failed"
+)
+
+// Add creates a new pre-pull Controller and adds it to the Manager with default RBAC,
+// if a namespace is set for the DaemonSet. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	if DefaultOptions.Namespace == "" {
+		return nil
+	}
+	return add(mgr, newReconciler(mgr, DefaultOptions))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, o Options) *ReconcilePrePull {
+	return &ReconcilePrePull{
+		Client:    mgr.GetClient(),
+		scheme:    mgr.GetScheme(),
+		namespace: o.Namespace,
+		allowList: o.allowList(),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r *ReconcilePrePull) error {
+	// Create a new controller
+	c, err := controller.New("prepull-controller", mgr, controller.Options{Reconciler: shutdown.Track(health.Track(r))})
+	if err != nil {
+		return err
+	}
+
+	// All changes are reconciled by the same request.
+	toKey := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(handler.MapObject) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: r.key()}}
+	})}
+	if len(r.allowList) == 0 {
+		// The images of the notebooks, which may come from their templates.
+		for _, obj := range []runtime.Object{&v1alpha1.Notebook{}, &v1alpha1.NotebookTemplate{}, &v1alpha1.ClusterNotebookTemplate{}} {
+			if err := c.Watch(&source.Kind{Type: obj}, toKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The DaemonSet and the status ConfigMap are reconciled when they change,
+	// and the status when the Pods of the DaemonSet do.
+	ownObjects := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+		if a.Meta.GetNamespace() != r.namespace {
+			return nil
+		}
+		switch a.Object.(type) {
+		case *corev1.Pod:
+			if a.Meta.GetLabels()["app"] != Name {
+				return nil
+			}
+		case *corev1.ConfigMap:
+			if a.Meta.GetName() != StatusConfigMapName {
+				return nil
+			}
+		default:
+			if a.Meta.GetName() != Name {
+				return nil
+			}
+		}
+		return []reconcile.Request{{NamespacedName: r.key()}}
+	})}
+	for _, obj := range []runtime.Object{&appsv1.DaemonSet{}, &corev1.Pod{}, &corev1.ConfigMap{}} {
+		if err := c.Watch(&source.Kind{Type: obj}, ownObjects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcilePrePull{}
+
+// ReconcilePrePull reconciles the pre-pull DaemonSet and its status ConfigMap.
+type ReconcilePrePull struct {
+	client.Client
+	scheme *runtime.Scheme
+	// namespace of the DaemonSet.
+	namespace string
+	// allowList are the images to pull. The images of the notebooks are pulled if it is empty.
+	allowList []string
+}
+
+// key is the key of the DaemonSet, and of the only request of the controller.
+func (r *ReconcilePrePull) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: r.namespace, Name: Name}
+}
+
+// Reconcile updates the DaemonSet to pull the current set of images, and reports
+// which images are pulled on each node in the status ConfigMap. The DaemonSet
+// is deleted, with the ConfigMap it owns, when there are no images to pull.
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubeflow.org,resources=notebooks,verbs=get;list;watch
+func (r *ReconcilePrePull) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	images, err := r.images()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(images) == 0 {
+		return reconcile.Result{}, r.deleteDaemonSet()
+	}
+	ds, err := r.reconcileDaemonSet(images)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, r.reconcileStatus(ds, images)
+}
+
+// images returns the sorted images to pull: the allow-list, or the images of
+// the containers and init containers of all notebooks. Notebooks whose template
+// or snapshot isn't ready are skipped, and so are notebooks with image pull
+// secrets, whose images the DaemonSet can't pull without the secrets of their
+// namespace.
+func (r *ReconcilePrePull) images() ([]string, error) {
+	if len(r.allowList) > 0 {
+		return sortedUnique(r.allowList), nil
+	}
+	notebooks := &v1alpha1.NotebookList{}
+	if err := r.List(context.TODO(), &client.ListOptions{}, notebooks); err != nil {
+		return nil, err
+	}
+	var images []string
+	for i := range notebooks.Items {
+		podSpec, err := podspec.Resolve(context.TODO(), r.Client, &notebooks.Items[i])
+		if podspec.IsPending(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if len(podSpec.ImagePullSecrets) > 0 {
+			continue
+		}
+		for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+			for _, c := range containers {
+				if c.Image != "" {
+					images = append(images, c.Image)
+				}
+			}
+		}
+	}
+	return sortedUnique(images), nil
+}
+
+func sortedUnique(images []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, image := range images {
+		if !seen[image] {
+			seen[image] = true
+			unique = append(unique, image)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// initContainerName is the name of the init container that pulls the image with the index.
+func initContainerName(index int) string {
+	return fmt.Sprintf("prepull-%v", index)
+}
+
+// prepullUser is the unprivileged user the containers of the DaemonSet run as.
+const prepullUser = 65534
+
+// newDaemonSet returns the DaemonSet that pulls the images. Each image is pulled
+// by an init container that exits right away, and the Pods then run the pause
+// image. The images must have /bin/sh, like the Jupyter images. The images are
+// chosen by the authors of notebooks, so the containers run unprivileged,
+// without capabilities, a writable root filesystem or the token of the service
+// account of the namespace.
+func newDaemonSet(namespace string, images []string) *appsv1.DaemonSet {
+	labels := map[string]string{"app": Name}
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1m"),
+			corev1.ResourceMemory: resource.MustParse("4Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+	securityContext := func() *corev1.SecurityContext {
+		nonRoot, noEscalation, readOnly := true, false, true
+		user := int64(prepullUser)
+		return &corev1.SecurityContext{
+			RunAsUser:                &user,
+			RunAsNonRoot:             &nonRoot,
+			AllowPrivilegeEscalation: &noEscalation,
+			ReadOnlyRootFilesystem:   &readOnly,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		}
+	}
+	var initContainers []corev1.Container
+	for i, image := range images {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            initContainerName(i),
+			Image:           image,
+			Command:         []string{"/bin/sh", "-c", "true"},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Resources:       resources,
+			SecurityContext: securityContext(),
+		})
+	}
+	automountToken := false
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      Name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: &automountToken,
+					InitContainers:               initContainers,
+					Containers: []corev1.Container{{
+						Name:            "pause",
+						Image:           PauseImage,
+						Resources:       resources,
+						SecurityContext: securityContext(),
+					}},
+				},
+			},
+		},
+	}
+	ds.Annotations = map[string]string{
+		util.PodTemplateHashAnnotation: util.PodTemplateHash(&ds.Spec.Template.Spec),
+	}
+	return ds
+}
+
+// reconcileDaemonSet creates or updates the DaemonSet, and returns it.
+func (r *ReconcilePrePull) reconcileDaemonSet(images []string) (*appsv1.DaemonSet, error) {
+	ds := newDaemonSet(r.namespace, images)
+	found := &appsv1.DaemonSet{}
+	err := r.Get(context.TODO(), r.key(), found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating DaemonSet", "namespace", ds.Namespace, "name", ds.Name, "images", images)
+		if err := r.Create(context.TODO(), ds); err != nil {
+			return nil, err
+		}
+		return ds, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if util.CopyDaemonSetFields(ds, found) {
+		log.Info("Updating DaemonSet", "namespace", ds.Namespace, "name", ds.Name, "images", images)
+		if err := r.Update(context.TODO(), found); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// deleteDaemonSet deletes the DaemonSet if it exists.
+func (r *ReconcilePrePull) deleteDaemonSet() error {
+	found := &appsv1.DaemonSet{}
+	err := r.Get(context.TODO(), r.key(), found)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	log.Info("Deleting DaemonSet without images to pull", "namespace", found.Namespace, "name", found.Name)
+	if err := r.Delete(context.TODO(), found); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// reconcileStatus writes the pull status of the images on each node to the
+// status ConfigMap, which is owned by the DaemonSet. Each node has a key
+// listing its images with their status, e.g. "jupyter:1: Pulled".
+func (r *ReconcilePrePull) reconcileStatus(ds *appsv1.DaemonSet, images []string) error {
+	pods := &corev1.PodList{}
+	if err := r.List(context.TODO(), client.InNamespace(r.namespace).MatchingLabels(map[string]string{"app": Name}), pods); err != nil {
+		return err
+	}
+	// A node may have an old Pod during a rollout, so the best status of
+	// each image over the Pods of the node is kept.
+	nodes := map[string]map[string]string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		statuses := nodes[pod.Spec.NodeName]
+		if statuses == nil {
+			statuses = map[string]string{}
+			nodes[pod.Spec.NodeName] = statuses
+		}
+		for image, status := range podStatuses(pod) {
+			if statuses[image] != StatusPulled {
+				statuses[image] = status
+			}
+		}
+	}
+	data := map[string]string{}
+	for node, statuses := range nodes {
+		var lines []string
+		for _, image := range images {
+			status, ok := statuses[image]
+			if !ok {
+				status = StatusPulling
+			}
+			lines = append(lines, image+": "+status)
+		}
+		data[node] = strings.Join(lines, "\n")
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      StatusConfigMapName,
+			Namespace: r.namespace,
+		},
+		Data: data,
+	}
+	if err := controllerutil.SetControllerReference(ds, cm, r.scheme); err != nil {
+		return err
+	}
+	found := &corev1.ConfigMap{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+		return r.Create(context.TODO(), cm)
+	} else if err != nil {
+		return err
+	}
+	if len(found.Data) != len(cm.Data) || len(cm.Data) > 0 && !reflect.DeepEqual(found.Data, cm.Data) {
+		found.Data = cm.Data
+		log.Info("Updating ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+		return r.Update(context.TODO(), found)
+	}
+	return nil
+}
+
+// podStatuses returns the pull status of the images of the init containers of
+// a Pod of the DaemonSet. Images whose container has no status yet are left out.
+func podStatuses(pod *corev1.Pod) map[string]string {
+	images := map[string]string{}
+	for _, c := range pod.Spec.InitContainers {
+		images[c.Name] = c.Image
+	}
+	statuses := map[string]string{}
+	for _, cs := range pod.Status.InitContainerStatuses {
+		image, ok := images[cs.Name]
+		if !ok {
+			continue
+		}
+		switch {
+		case cs.State.Terminated != nil || cs.State.Running != nil:
+			statuses[image] = StatusPulled
+		case cs.State.Waiting != nil && isPullFailure(cs.State.Waiting.Reason):
+			statuses[image] = StatusFailed + ": " + cs.State.Waiting.Reason
+		default:
+			statuses[image] = StatusPulling
+		}
+	}
+	return statuses
+}
+
+// isPullFailure returns true for the reasons of containers whose image can't be pulled.
+func isPullFailure(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prepull
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func init() {
+	// The fake client decodes objects with the client-go scheme.
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+const testNamespace = "kubeflow"
+
+func newTestNotebook(name string, images ...string) *v1alpha1.Notebook {
+	nb := &v1alpha1.Notebook{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}}
+	for _, image := range images {
+		nb.Spec.Template.Spec.Containers = append(nb.Spec.Template.Spec.Containers, corev1.Container{Name: image, Image: image})
+	}
+	return nb
+}
+
+func newTestReconciler(o Options, objs ...runtime.Object) *ReconcilePrePull {
+	o.Namespace = testNamespace
+	return &ReconcilePrePull{
+		Client:    fake.NewFakeClient(objs...),
+		scheme:    scheme.Scheme,
+		namespace: o.Namespace,
+		allowList: o.allowList(),
+	}
+}
+
+// reconcileImages runs a reconcile and returns the images pulled by the
+// DaemonSet, or nil if there is none.
+func reconcileImages(t *testing.T, r *ReconcilePrePull) []string {
+	t.Helper()
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: r.key()}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := r.Get(context.TODO(), r.key(), ds); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		t.Fatalf("Get DaemonSet: %v", err)
+	}
+	var images []string
+	for _, c := range ds.Spec.Template.Spec.InitContainers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+func TestReconcileImages(t *testing.T) {
+	nb := newTestNotebook("nb", "jupyter:1", "proxy:1")
+	r := newTestReconciler(Options{}, nb, newTestNotebook("other", "jupyter:1"))
+	want := []string{"jupyter:1", "proxy:1"}
+	if got := reconcileImages(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %v; want %v", got, want)
+	}
+
+	// Images are updated as notebooks come and go.
+	if err := r.Delete(context.TODO(), nb); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(context.TODO(), newTestNotebook("new", "jupyter:2")); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"jupyter:1", "jupyter:2"}
+	if got := reconcileImages(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("images after changes = %v; want %v", got, want)
+	}
+
+	// The DaemonSet is deleted without notebooks.
+	for _, name := range []string{"other", "new"} {
+		if err := r.Delete(context.TODO(), newTestNotebook(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := reconcileImages(t, r); got != nil {
+		t.Errorf("images without notebooks = %v; want no DaemonSet", got)
+	}
+}
+
+func TestReconcileImagesWithPullSecrets(t *testing.T) {
+	private := newTestNotebook("private", "registry.example.com/private:1")
+	private.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+	r := newTestReconciler(Options{}, private, newTestNotebook("nb", "jupyter:1"))
+	want := []string{"jupyter:1"}
+	if got := reconcileImages(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %v; want %v without the images needing pull secrets", got, want)
+	}
+}
+
+func TestDaemonSetSecurity(t *testing.T) {
+	spec := newDaemonSet(testNamespace, []string{"jupyter:1"}).Spec.Template.Spec
+	if spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken {
+		t.Errorf("the service account token is mounted")
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		sc := c.SecurityContext
+		if sc == nil || sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot || sc.RunAsUser == nil || *sc.RunAsUser == 0 ||
+			sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation ||
+			sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem ||
+			sc.Capabilities == nil || !reflect.DeepEqual(sc.Capabilities.Drop, []corev1.Capability{"ALL"}) {
+			t.Errorf("container %v has security context %+v; want it restricted", c.Name, sc)
+		}
+	}
+}
+
+func TestReconcileAllowList(t *testing.T) {
+	r := newTestReconciler(Options{Images: "tensorflow:1, jupyter:1,"}, newTestNotebook("nb", "other:1"))
+	want := []string{"jupyter:1", "tensorflow:1"}
+	if got := reconcileImages(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %v; want %v", got, want)
+	}
+}
+
+func TestReconcileStatus(t *testing.T) {
+	waiting := func(reason string) corev1.ContainerState {
+		return corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}
+	}
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	newPod := func(name, node string, states ...corev1.ContainerState) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: map[string]string{"app": Name}},
+			Spec:       newDaemonSet(testNamespace, []string{"jupyter:1", "proxy:1"}).Spec.Template.Spec,
+		}
+		pod.Spec.NodeName = node
+		for i, state := range states {
+			pod.Status.InitContainerStatuses = append(pod.Status.InitContainerStatuses,
+				corev1.ContainerStatus{Name: initContainerName(i), State: state})
+		}
+		return pod
+	}
+	r := newTestReconciler(Options{},
+		newTestNotebook("nb", "jupyter:1", "proxy:1"),
+		newPod("a", "node-a", terminated, terminated),
+		newPod("b", "node-b", terminated, waiting("PodInitializing")),
+		newPod("c", "node-c", waiting("ImagePullBackOff")),
+		newPod("unscheduled", ""),
+	)
+	reconcileImages(t, r)
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: StatusConfigMapName, Namespace: testNamespace}, cm); err != nil {
+		t.Fatalf("Get ConfigMap: %v", err)
+	}
+	want := map[string]string{
+		"node-a": "jupyter:1: Pulled\nproxy:1: Pulled",
+		"node-b": "jupyter:1: Pulled\nproxy:1: Pulling",
+		"node-c": "jupyter:1: Failed: ImagePullBackOff\nproxy:1: Pulling",
+	}
+	if !reflect.DeepEqual(cm.Data, want) {
+		t.Errorf("status = %v; want %v", cm.Data, want)
+	}
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].Name != Name {
+		t.Errorf("status owners = %v; want the DaemonSet", cm.OwnerReferences)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package culler decides when an idle notebook should be scaled down.
+package culler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+)
+
+// LastActivityAnnotation records the last time the notebook was used, in RFC3339 format.
+// The controller keeps it up to date while the notebook is running. Setting it to the
+// current time on a culled notebook ("touching" it) scales the notebook back up.
+const LastActivityAnnotation = "notebooks.kubeflow.org/last-activity"
+
+// ActivitySource reports when a notebook was last used.
+type ActivitySource interface {
+	// LastActivity returns the time of the most recent kernel or HTTP activity
+	// of the notebook server.
+	LastActivity(nb *v1alpha1.Notebook) (time.Time, error)
+}
+
+// JupyterActivitySource reads the activity of a notebook from the Jupyter REST API.
+type JupyterActivitySource struct {
+	Client *http.Client
+	// BaseURL returns the URL under which the Jupyter server of nb is served.
+	BaseURL func(nb *v1alpha1.Notebook) string
+}
+
+// NewJupyterActivitySource returns an ActivitySource that talks to the notebook
+// server through its cluster Service.
+func NewJupyterActivitySource() *JupyterActivitySource {
+	return &JupyterActivitySource{
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		BaseURL: serviceURL,
+	}
+}
+
+// serviceURL is the in-cluster URL of the notebook server.
+func serviceURL(nb *v1alpha1.Notebook) string {
+	return "http://" + routing.ServiceHost(nb) + routing.BaseURL(nb)
+}
+
+// jupyterStatus is the response of GET /api/status.
+type jupyterStatus struct {
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// jupyterKernel is an item of the response of GET /api/kernels.
+type jupyterKernel struct {
+	LastActivity   time.Time `json:"last_activity"`
+	ExecutionState string    `json:"execution_state"`
+}
+
+// LastActivity returns the most recent of the server's HTTP activity and the
+// activity of its kernels. A busy kernel counts as activity now.
+func (s *JupyterActivitySource) LastActivity(nb *v1alpha1.Notebook) (time.Time, error) {
+	base := s.BaseURL(nb)
+
+	status := jupyterStatus{}
+	if err := s.get(base+"/api/status", &status); err != nil {
+		return time.Time{}, err
+	}
+	last := status.LastActivity
+
+	kernels := []jupyterKernel{}
+	if err := s.get(base+"/api/kernels", &kernels); err != nil {
+		return time.Time{}, err
+	}
+	for _, k := range kernels {
+		if k.ExecutionState == "busy" {
+			return time.Now(), nil
+		}
+		if k.LastActivity.After(last) {
+			last = k.LastActivity
+		}
+	}
+	return last, nil
+}
+
+func (s *JupyterActivitySource) get(url string, v interface{}) error {
+	resp, err := s.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v: unexpected status %v", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Culler scales notebooks down after they have been idle for IdleTime.
+type Culler struct {
+	Source ActivitySource
+	// IdleTime is how long a notebook may be unused before it is culled.
+	IdleTime time.Duration
+	// CheckPeriod is how often the activity of a running notebook is polled.
+	CheckPeriod time.Duration
+	// Now returns the current time. It can be replaced in tests.
+	Now func() time.Time
+}
+
+// New returns a Culler using the given activity source.
+func New(source ActivitySource, idleTime, checkPeriod time.Duration) *Culler {
+	return &Culler{
+		Source:      source,
+		IdleTime:    idleTime,
+		CheckPeriod: checkPeriod,
+		Now:         time.Now,
+	}
+}
+
+// LastActivity returns the last recorded activity of nb. Notebooks that have
+// never been seen active count from their creation.
+func LastActivity(nb *v1alpha1.Notebook) time.Time {
+	if v, ok := nb.Annotations[LastActivityAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return nb.CreationTimestamp.Time
+}
+
+// UpdateActivity polls the activity source and records a newer activity in the
+// annotations of nb. It returns true if the annotations were changed.
+func (c *Culler) UpdateActivity(nb *v1alpha1.Notebook) (bool, error) {
+	t, err := c.Source.LastActivity(nb)
+	if err != nil {
+		return false, err
+	}
+	// The annotation only has second precision.
+	t = t.UTC().Truncate(time.Second)
+	if !t.After(LastActivity(nb)) {
+		return false, nil
+	}
+	if nb.Annotations == nil {
+		nb.Annotations = map[string]string{}
+	}
+	nb.Annotations[LastActivityAnnotation] = t.Format(time.RFC3339)
+	return true, nil
+}
+
+// Touch records activity of nb now, e.g. when a stopped notebook is resumed.
+func (c *Culler) Touch(nb *v1alpha1.Notebook) {
+	if nb.Annotations == nil {
+		nb.Annotations = map[string]string{}
+	}
+	nb.Annotations[LastActivityAnnotation] = c.Now().UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+// IsIdle returns true if nb has not been used for longer than its idle time.
+// The culling annotations of nb can disable culling or override IdleTime.
+func (c *Culler) IsIdle(nb *v1alpha1.Notebook) bool {
+	if nb.Annotations[v1alpha1.CullingDisabledAnnotation] == "true" {
+		return false
+	}
+	idleTime := c.IdleTime
+	if v, ok := nb.Annotations[v1alpha1.IdleTimeAnnotation]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			idleTime = d
+		}
+	}
+	return c.Now().Sub(LastActivity(nb)) > idleTime
+}
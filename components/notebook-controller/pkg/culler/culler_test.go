@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package culler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeJupyter serves the parts of the Jupyter REST API used by JupyterActivitySource.
+func fakeJupyter(status, kernels string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ns/nb/api/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(status))
+	})
+	mux.HandleFunc("/ns/nb/api/kernels", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(kernels))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newNotebook(annotations map[string]string) *v1alpha1.Notebook {
+	return &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "nb",
+			Namespace:         "ns",
+			Annotations:       annotations,
+			CreationTimestamp: metav1.NewTime(time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+}
+
+func TestJupyterActivitySource(t *testing.T) {
+	type TestCase struct {
+		Status   string
+		Kernels  string
+		Expected time.Time
+		Busy     bool
+	}
+	cases := []TestCase{
+		{
+			Status:   `{"last_activity": "2019-02-20T10:00:00.123456Z"}`,
+			Kernels:  `[]`,
+			Expected: time.Date(2019, 2, 20, 10, 0, 0, 123456000, time.UTC),
+		},
+		{
+			Status:   `{"last_activity": "2019-02-20T10:00:00Z"}`,
+			Kernels:  `[{"last_activity": "2019-02-20T11:00:00Z", "execution_state": "idle"}]`,
+			Expected: time.Date(2019, 2, 20, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			Status:  `{"last_activity": "2019-02-20T10:00:00Z"}`,
+			Kernels: `[{"last_activity": "2019-02-20T09:00:00Z", "execution_state": "busy"}]`,
+			Busy:    true,
+		},
+	}
+
+	for _, c := range cases {
+		server := fakeJupyter(c.Status, c.Kernels)
+		source := NewJupyterActivitySource()
+		source.BaseURL = func(nb *v1alpha1.Notebook) string {
+			return server.URL + "/" + nb.Namespace + "/" + nb.Name
+		}
+		before := time.Now()
+		result, err := source.LastActivity(newNotebook(nil))
+		server.Close()
+		if err != nil {
+			t.Errorf("LastActivity with status %v returned error: %v", c.Status, err)
+			continue
+		}
+		if c.Busy {
+			if result.Before(before) {
+				t.Errorf("LastActivity with busy kernel = %v; want now", result)
+			}
+			continue
+		}
+		if !result.Equal(c.Expected) {
+			t.Errorf("LastActivity(%v, %v) = %v; want %v", c.Status, c.Kernels, result, c.Expected)
+		}
+	}
+}
+
+type fakeSource struct {
+	last time.Time
+}
+
+func (f *fakeSource) LastActivity(nb *v1alpha1.Notebook) (time.Time, error) {
+	return f.last, nil
+}
+
+func TestCuller(t *testing.T) {
+	now := time.Date(2019, 2, 20, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{}
+	c := New(source, time.Hour, time.Minute)
+	c.Now = func() time.Time { return now }
+
+	nb := newNotebook(nil)
+	if !c.IsIdle(nb) {
+		t.Errorf("notebook created %v without activity should be idle at %v", nb.CreationTimestamp, now)
+	}
+
+	source.last = now.Add(-10 * time.Minute)
+	changed, err := c.UpdateActivity(nb)
+	if err != nil || !changed {
+		t.Fatalf("UpdateActivity = %v, %v; want true, nil", changed, err)
+	}
+	if got := nb.Annotations[LastActivityAnnotation]; got != "2019-02-20T11:50:00Z" {
+		t.Errorf("last activity annotation = %v; want 2019-02-20T11:50:00Z", got)
+	}
+	if c.IsIdle(nb) {
+		t.Errorf("notebook active 10 minutes ago should not be idle")
+	}
+
+	// An older activity must not move the annotation back.
+	source.last = now.Add(-2 * time.Hour)
+	if changed, _ := c.UpdateActivity(nb); changed {
+		t.Errorf("UpdateActivity with older activity changed the annotation")
+	}
+
+	// Touching a culled notebook wakes it up.
+	nb = newNotebook(map[string]string{LastActivityAnnotation: "2019-02-20T08:00:00Z"})
+	if !c.IsIdle(nb) {
+		t.Errorf("notebook inactive for 4 hours should be idle")
+	}
+	nb.Annotations[LastActivityAnnotation] = now.Format(time.RFC3339)
+	if c.IsIdle(nb) {
+		t.Errorf("touched notebook should not be idle")
+	}
+
+	// The culling annotations override the culler settings.
+	nb = newNotebook(map[string]string{
+		LastActivityAnnotation:             "2019-02-20T08:00:00Z",
+		v1alpha1.CullingDisabledAnnotation: "true",
+	})
+	if c.IsIdle(nb) {
+		t.Errorf("notebook with culling disabled should not be idle")
+	}
+	nb = newNotebook(map[string]string{
+		LastActivityAnnotation:      "2019-02-20T08:00:00Z",
+		v1alpha1.IdleTimeAnnotation: "5h0m0s",
+	})
+	if c.IsIdle(nb) {
+		t.Errorf("notebook inactive for 4 hours with an idle time of 5 hours should not be idle")
+	}
+}
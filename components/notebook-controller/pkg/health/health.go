@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health serves the liveness and readiness probes of the manager.
+// Each probe runs a list of named checks and fails if any of them fails.
+package health
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// Check returns an error if the checked component is unhealthy.
+type Check func() error
+
+// Handler serves a probe. It responds with 200 if all checks pass, and with
+// 500 and the failed checks otherwise. The verbose query parameter lists all checks.
+type Handler struct {
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+// AddCheck adds a named check to the probe.
+func (h *Handler) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.checks == nil {
+		h.checks = map[string]Check{}
+	}
+	h.checks[name] = check
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	var names []string
+	checks := map[string]Check{}
+	for name, check := range h.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	h.mu.Unlock()
+	sort.Strings(names)
+
+	_, verbose := req.URL.Query()["verbose"]
+	failed := false
+	var out []string
+	for _, name := range names {
+		if err := checks[name](); err != nil {
+			failed = true
+			out = append(out, fmt.Sprintf("[-]%v failed: %v", name, err))
+		} else if verbose {
+			out = append(out, fmt.Sprintf("[+]%v ok", name))
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if failed {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	for _, line := range out {
+		fmt.Fprintln(w, line)
+	}
+	if failed {
+		fmt.Fprintln(w, "check failed")
+	} else {
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// Ping always passes, so that a probe without other checks tells that the
+// process serves requests.
+func Ping() error {
+	return nil
+}
+
+// CacheSynced returns a check that passes once the informers of the cache have
+// synced. The cache of a manager only starts when the manager does, e.g. once
+// it is elected leader.
+func CacheSynced(c cache.Cache, stop <-chan struct{}) Check {
+	var mu sync.Mutex
+	synced := false
+	go func() {
+		if c.WaitForCacheSync(stop) {
+			mu.Lock()
+			synced = true
+			mu.Unlock()
+		}
+	}()
+	return func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !synced {
+			return fmt.Errorf("the informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// ServingCertValid returns a check that passes if the serving certificate
+// cert.pem written to certDir by the webhook server is valid at the time of the check.
+func ServingCertValid(certDir string, now func() time.Time) Check {
+	return func() error {
+		data, err := ioutil.ReadFile(filepath.Join(certDir, "cert.pem"))
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return fmt.Errorf("no PEM data in the serving certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		t := now()
+		if t.Before(cert.NotBefore) {
+			return fmt.Errorf("the serving certificate is not valid before %v", cert.NotBefore)
+		}
+		if t.After(cert.NotAfter) {
+			return fmt.Errorf("the serving certificate expired at %v", cert.NotAfter)
+		}
+		return nil
+	}
+}
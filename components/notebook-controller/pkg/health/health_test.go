@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestHandler(t *testing.T) {
+	h := &Handler{}
+	h.AddCheck("ping", Ping)
+	tests := []struct {
+		url      string
+		failing  error
+		wantCode int
+		wantBody string
+	}{
+		{url: "/readyz", wantCode: http.StatusOK, wantBody: "ok\n"},
+		{url: "/readyz?verbose", wantCode: http.StatusOK, wantBody: "[+]cache ok\n[+]ping ok\nok\n"},
+		{url: "/readyz", failing: fmt.Errorf("not synced"), wantCode: http.StatusInternalServerError,
+			wantBody: "[-]cache failed: not synced\ncheck failed\n"},
+	}
+	for _, test := range tests {
+		err := test.failing
+		h.AddCheck("cache", func() error { return err })
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody {
+			t.Errorf("GET %v with error %v = %v %q; want %v %q", test.url, test.failing, w.Code, w.Body.String(), test.wantCode, test.wantBody)
+		}
+	}
+}
+
+func TestReconcilesCheck(t *testing.T) {
+	now := time.Now()
+	rs := &Reconciles{Now: func() time.Time { return now }}
+	check := rs.Check(10 * time.Minute)
+	var fail bool
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	r := rs.Track(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		started <- struct{}{}
+		<-release
+		if fail {
+			return reconcile.Result{}, fmt.Errorf("failed")
+		}
+		return reconcile.Result{}, nil
+	}))
+	reconcileNow := func() {
+		release <- struct{}{}
+	}
+
+	if err := check(); err != nil {
+		t.Errorf("check without reconciles = %v; want nil", err)
+	}
+
+	// A reconcile that hangs.
+	done := make(chan struct{})
+	go func() {
+		r.Reconcile(reconcile.Request{})
+		close(done)
+	}()
+	<-started
+	now = now.Add(11 * time.Minute)
+	if err := check(); err == nil {
+		t.Errorf("check with a hung reconcile = nil; want an error")
+	}
+	reconcileNow()
+	<-done
+	if err := check(); err != nil {
+		t.Errorf("check after a successful reconcile = %v; want nil", err)
+	}
+
+	// Reconciles that keep failing are retried, and don't make the
+	// controller unhealthy.
+	fail = true
+	go reconcileNow()
+	r.Reconcile(reconcile.Request{})
+	<-started
+	now = now.Add(11 * time.Minute)
+	if err := check(); err != nil {
+		t.Errorf("check after failing for 11 minutes = %v; want nil", err)
+	}
+}
+
+func TestServingCertValid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	notBefore := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeCert(t, dir, notBefore, notBefore.AddDate(1, 0, 0))
+
+	for _, test := range []struct {
+		now   time.Time
+		valid bool
+	}{
+		{notBefore.AddDate(0, 6, 0), true},
+		{notBefore.AddDate(0, 0, -1), false},
+		{notBefore.AddDate(1, 0, 1), false},
+	} {
+		err := ServingCertValid(dir, func() time.Time { return test.now })()
+		if (err == nil) != test.valid {
+			t.Errorf("check at %v = %v; want valid = %v", test.now, err, test.valid)
+		}
+	}
+	if err := ServingCertValid(filepath.Join(dir, "missing"), time.Now)(); err == nil {
+		t.Errorf("check without certificate = nil; want an error")
+	}
+}
+
+func writeCert(t *testing.T, dir string, notBefore, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "notebook-admission-server-service"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciles keeps the start times of the running reconciles of the
+// reconcilers it wraps, to tell a hung controller from one that is busy or has
+// nothing to do.
+type Reconciles struct {
+	mu sync.Mutex
+	// Now returns the current time. It is time.Now if nil.
+	Now func() time.Time
+	// running holds the start times of the running reconciles.
+	running map[int]time.Time
+	nextID  int
+}
+
+// DefaultReconciles are the reconciles of the controllers of the manager.
+var DefaultReconciles = &Reconciles{}
+
+// Track wraps r so that DefaultReconciles keeps the times of its reconciles.
+func Track(r reconcile.Reconciler) reconcile.Reconciler {
+	return DefaultReconciles.Track(r)
+}
+
+// Track wraps r so that the times of its reconciles are kept.
+func (rs *Reconciles) Track(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		id := rs.start()
+		defer rs.done(id)
+		return r.Reconcile(request)
+	})
+}
+
+// Check returns a check that fails if a reconcile has been running for longer
+// than maxAge, so that the workers of the controllers make no progress. Failed
+// reconciles pass: they are retried with backoff, and are reported by the
+// controllers through events and metrics, so that an object that can't be
+// reconciled doesn't get the controller restarted.
+func (rs *Reconciles) Check(maxAge time.Duration) Check {
+	return func() error {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		now := rs.now()
+		for _, started := range rs.running {
+			if now.Sub(started) > maxAge {
+				return fmt.Errorf("a reconcile has been running since %v", started)
+			}
+		}
+		return nil
+	}
+}
+
+func (rs *Reconciles) now() time.Time {
+	if rs.Now != nil {
+		return rs.Now()
+	}
+	return time.Now()
+}
+
+func (rs *Reconciles) start() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.running == nil {
+		rs.running = map[int]time.Time{}
+	}
+	rs.nextID++
+	rs.running[rs.nextID] = rs.now()
+	return rs.nextID
+}
+
+func (rs *Reconciles) done(id int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	delete(rs.running, id)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration rewrites the stored Notebooks in the storage version of the CRD.
+package migration
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("migration")
+
+// CRDName is the name of the Notebook CustomResourceDefinition.
+const CRDName = "notebooks.kubeflow.org"
+
+var crdKind = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1beta1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// Migrator rewrites all Notebooks so that the API server stores them in the
+// storage version, v1beta1, and then removes the older versions from the
+// stored versions of the CRD. The older versions can then be dropped from the CRD.
+// The API server reads the stored Notebooks through the conversion webhook,
+// so the migration fails until the CA bundle of the webhook is set.
+type Migrator struct {
+	Client client.Client
+	// RetryPeriod is how long to wait after a failed migration, e.g. while
+	// the webhook server isn't serving or trusted yet.
+	RetryPeriod time.Duration
+}
+
+var _ manager.Runnable = &Migrator{}
+
+// Start runs the migration until it succeeds, then waits for stop.
+func (m *Migrator) Start(stop <-chan struct{}) error {
+	err := wait.PollImmediateUntil(m.RetryPeriod, func() (bool, error) {
+		if err := m.Migrate(); err != nil {
+			log.Error(err, "storage version migration failed")
+			return false, nil
+		}
+		return true, nil
+	}, stop)
+	if err != nil && err != wait.ErrWaitTimeout {
+		return err
+	}
+	// The manager stops when one of its runnables returns.
+	<-stop
+	return nil
+}
+
+// Migrate runs the migration once.
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+func (m *Migrator) Migrate() error {
+	ctx := context.TODO()
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdKind)
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: CRDName}, crd); err != nil {
+		return err
+	}
+
+	storageVersion := []string{v1beta1.SchemeGroupVersion.Version}
+	stored, _, err := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(stored, storageVersion) {
+		return nil
+	}
+
+	// Updating an object, even without changes, makes the API server
+	// write it in the storage version.
+	notebooks := &v1beta1.NotebookList{}
+	if err := m.Client.List(ctx, &client.ListOptions{}, notebooks); err != nil {
+		return err
+	}
+	for i := range notebooks.Items {
+		nb := &notebooks.Items[i]
+		log.Info("Migrating Notebook", "namespace", nb.Namespace, "name", nb.Name, "from", stored)
+		if err := m.Client.Update(ctx, nb); err != nil {
+			return err
+		}
+	}
+
+	if err := unstructured.SetNestedStringSlice(crd.Object, storageVersion, "status", "storedVersions"); err != nil {
+		return err
+	}
+	log.Info("Updating the stored versions of the CRD", "name", CRDName, "storedVersions", storageVersion)
+	return m.Client.Status().Update(ctx, crd)
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podspec resolves the PodSpec a notebook runs, which the controller
+// and the admission webhooks both need.
+package podspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Resolve returns the PodSpec of the notebook. The PodSpec of a notebook with a
+// templateRef is merged into the one of its template, and the one of a notebook
+// with cloneFrom into the one of its snapshot, and defaulted.
+func Resolve(ctx context.Context, c client.Client, nb *v1alpha1.Notebook) (*corev1.PodSpec, error) {
+	var base *corev1.PodSpec
+	switch {
+	case nb.Spec.CloneFrom != "":
+		snapshot, err := ReadySnapshot(ctx, c, nb)
+		if err != nil {
+			return nil, err
+		}
+		base = &snapshot.Status.NotebookSpec.Template.Spec
+	case nb.Spec.TemplateRef != nil:
+		template, err := getTemplate(ctx, c, nb)
+		if err != nil {
+			return nil, err
+		}
+		base = &template.Spec
+	default:
+		return &nb.Spec.Template.Spec, nil
+	}
+
+	podSpec, err := Merge(base, &nb.Spec.Template.Spec)
+	if err != nil {
+		return nil, err
+	}
+	v1alpha1.SetPodSpecDefaults(podSpec)
+	return podSpec, nil
+}
+
+// getTemplate returns the template of the notebook.
+func getTemplate(ctx context.Context, c client.Client, nb *v1alpha1.Notebook) (*v1alpha1.NotebookTemplateSpec, error) {
+	ref := nb.Spec.TemplateRef
+	switch TemplateKind(ref) {
+	case v1alpha1.ClusterNotebookTemplateKind:
+		t := &v1alpha1.ClusterNotebookTemplate{}
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name}, t); err != nil {
+			return nil, err
+		}
+		return &t.Spec.Template, nil
+	default:
+		t := &v1alpha1.NotebookTemplate{}
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: nb.Namespace}, t); err != nil {
+			return nil, err
+		}
+		return &t.Spec.Template, nil
+	}
+}
+
+// snapshotNotReadyError is returned for a notebook cloned from a snapshot that
+// isn't ready to use yet.
+type snapshotNotReadyError struct {
+	name string
+}
+
+func (e *snapshotNotReadyError) Error() string {
+	return fmt.Sprintf("NotebookSnapshot %v is not ready to use", e.name)
+}
+
+// IsPending returns true if the error says the template or snapshot of a
+// notebook doesn't exist or isn't ready to use yet. The PodSpec of the notebook
+// can be resolved once it is.
+func IsPending(err error) bool {
+	_, ok := err.(*snapshotNotReadyError)
+	return ok || errors.IsNotFound(err)
+}
+
+// ReadySnapshot returns the snapshot the notebook is cloned from, if it is ready to use.
+func ReadySnapshot(ctx context.Context, c client.Client, nb *v1alpha1.Notebook) (*v1alpha1.NotebookSnapshot, error) {
+	snapshot := &v1alpha1.NotebookSnapshot{}
+	if err := c.Get(ctx, types.NamespacedName{Name: nb.Spec.CloneFrom, Namespace: nb.Namespace}, snapshot); err != nil {
+		return nil, err
+	}
+	if !snapshot.Status.ReadyToUse || snapshot.Status.NotebookSpec == nil {
+		return nil, &snapshotNotReadyError{name: snapshot.Name}
+	}
+	return snapshot, nil
+}
+
+// TemplateKind returns the kind of the template ref refers to.
+func TemplateKind(ref *v1alpha1.TemplateReference) string {
+	if ref.Kind == "" {
+		return v1alpha1.NotebookTemplateKind
+	}
+	return ref.Kind
+}
+
+// Merge returns the template with the fields set in override replaced.
+// Like with kubectl apply, containers, volumes and the like are merged by name,
+// and the fields of a container are merged with the ones of the template
+// container of the same name.
+func Merge(template, override *corev1.PodSpec) (*corev1.PodSpec, error) {
+	original, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	// Fields that aren't set, like the containers of a notebook that takes them
+	// all from its template, are encoded as null, which would delete them.
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(override)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if v == nil {
+			delete(fields, k)
+		}
+	}
+	// The merged lists would start with the items added by override, but the
+	// first container of the template must stay the notebook container.
+	var containers, volumes []string
+	for _, c := range template.Containers {
+		containers = append(containers, c.Name)
+	}
+	for _, c := range override.Containers {
+		containers = append(containers, c.Name)
+	}
+	for _, v := range template.Volumes {
+		volumes = append(volumes, v.Name)
+	}
+	for _, v := range override.Volumes {
+		volumes = append(volumes, v.Name)
+	}
+	if len(containers) > 0 {
+		fields["$setElementOrder/containers"] = elementOrder(containers)
+	}
+	if len(volumes) > 0 {
+		fields["$setElementOrder/volumes"] = elementOrder(volumes)
+	}
+
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, corev1.PodSpec{})
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &corev1.PodSpec{}
+	if err := json.Unmarshal(merged, podSpec); err != nil {
+		return nil, err
+	}
+	return podSpec, nil
+}
+
+// elementOrder returns the $setElementOrder directive of a strategic merge patch
+// for a list merged by name, with the first occurrence of each name in order.
+func elementOrder(names []string) []map[string]string {
+	order := []map[string]string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			order = append(order, map[string]string{"name": name})
+		}
+	}
+	return order
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podspec
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+func TestMerge(t *testing.T) {
+	template := corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "notebook",
+			Image: "tensorflow:1.12",
+			Env:   []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+			},
+		}},
+		Volumes: []corev1.Volume{{
+			Name:         "datasets",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}},
+		NodeSelector: map[string]string{"pool": "cpu"},
+	}
+	tests := []struct {
+		name     string
+		override corev1.PodSpec
+		want     corev1.PodSpec
+	}{
+		{
+			name:     "no overrides",
+			override: corev1.PodSpec{},
+			want:     template,
+		},
+		{
+			name: "override fields of the notebook container",
+			override: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "notebook",
+					Env:  []corev1.EnvVar{{Name: "B", Value: "3"}},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+					},
+				}},
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "notebook",
+					Image: "tensorflow:1.12",
+					Env:   []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "3"}},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("4Gi"),
+						},
+					},
+				}},
+				Volumes:      template.Volumes,
+				NodeSelector: template.NodeSelector,
+			},
+		},
+		{
+			name: "add a sidecar and a volume",
+			override: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "proxy", Image: "proxy:1"}},
+				Volumes: []corev1.Volume{{
+					Name:         "scratch",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}},
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{template.Containers[0], {Name: "proxy", Image: "proxy:1"}},
+				Volumes: []corev1.Volume{template.Volumes[0], {
+					Name:         "scratch",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}},
+				NodeSelector: template.NodeSelector,
+			},
+		},
+	}
+	for _, test := range tests {
+		got, err := Merge(&template, &test.override)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !equality.Semantic.DeepEqual(*got, test.want) {
+			t.Errorf("%v: merged PodSpec differs: %v", test.name, diff.ObjectReflectDiff(test.want, *got))
+		}
+	}
+}
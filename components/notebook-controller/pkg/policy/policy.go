@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy checks notebooks against the NotebookPolicies of their
+// namespace. The validating webhook uses it to reject notebooks, and the
+// controller to report the violations of existing ones.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podSpecPath is the path of the PodSpec in a notebook.
+var podSpecPath = field.NewPath("spec", "template", "spec")
+
+// List returns the policies of the namespace.
+func List(ctx context.Context, c client.Client, namespace string) ([]v1alpha1.NotebookPolicy, error) {
+	policies := &v1alpha1.NotebookPolicyList{}
+	if err := c.List(ctx, client.InNamespace(namespace), policies); err != nil {
+		return nil, err
+	}
+	return policies.Items, nil
+}
+
+// Check returns the violations of the policy by a notebook running podSpec.
+func Check(policy *v1alpha1.NotebookPolicy, podSpec *corev1.PodSpec) field.ErrorList {
+	errs := field.ErrorList{}
+	spec := &policy.Spec
+
+	if len(spec.AllowedImages) > 0 {
+		check := func(containers []corev1.Container, fldPath *field.Path) {
+			for i, c := range containers {
+				if !imageAllowed(c.Image, spec.AllowedImages) {
+					errs = append(errs, field.Forbidden(fldPath.Index(i).Child("image"),
+						fmt.Sprintf("image %q is not allowed by NotebookPolicy %v", c.Image, policy.Name)))
+				}
+			}
+		}
+		check(podSpec.InitContainers, podSpecPath.Child("initContainers"))
+		check(podSpec.Containers, podSpecPath.Child("containers"))
+	}
+
+	// Sort the resources for stable messages.
+	var names []string
+	for name := range spec.MaxResources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		name := corev1.ResourceName(n)
+		max := spec.MaxResources[name]
+		requests, limits, unlimited := total(podSpec.Containers, name)
+		fldPath := podSpecPath.Child("containers")
+		if unlimited {
+			errs = append(errs, field.Forbidden(fldPath, fmt.Sprintf(
+				"all containers must set a %v limit, as NotebookPolicy %v limits it", name, policy.Name)))
+		}
+		if requests.Cmp(max) > 0 {
+			errs = append(errs, field.Forbidden(fldPath, fmt.Sprintf(
+				"the %v requests %v exceed the maximum %v of NotebookPolicy %v", name, requests.String(), max.String(), policy.Name)))
+		}
+		if limits.Cmp(max) > 0 {
+			errs = append(errs, field.Forbidden(fldPath, fmt.Sprintf(
+				"the %v limits %v exceed the maximum %v of NotebookPolicy %v", name, limits.String(), max.String(), policy.Name)))
+		}
+	}
+	return errs
+}
+
+// CheckCount returns the violation of the maximum number of notebooks of the
+// policy by the notebook with the given index among the notebooks of the
+// namespace, oldest first. It returns nil if the policy isn't violated.
+func CheckCount(policy *v1alpha1.NotebookPolicy, index int) *field.Error {
+	max := policy.Spec.MaxNotebooks
+	if max == nil || index < int(*max) {
+		return nil
+	}
+	return field.Forbidden(field.NewPath("metadata", "namespace"), fmt.Sprintf(
+		"the namespace may have at most %v notebooks by NotebookPolicy %v", *max, policy.Name))
+}
+
+// HasMaxNotebooks returns true if any of the policies limits the number of notebooks.
+func HasMaxNotebooks(policies []v1alpha1.NotebookPolicy) bool {
+	for i := range policies {
+		if policies[i].Spec.MaxNotebooks != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// imageAllowed returns true if the image matches one of the patterns.
+func imageAllowed(image string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, image); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// total returns the sum of the requests and limits of the containers for the
+// resource, and whether a container has no limit for it. A container without a
+// request for the resource requests its limit, like in Kubernetes.
+func total(containers []corev1.Container, name corev1.ResourceName) (requests, limits resource.Quantity, unlimited bool) {
+	for _, c := range containers {
+		limit, hasLimit := c.Resources.Limits[name]
+		if hasLimit {
+			limits.Add(limit)
+		} else {
+			unlimited = true
+		}
+		if request, ok := c.Resources.Requests[name]; ok {
+			requests.Add(request)
+		} else if hasLimit {
+			requests.Add(limit)
+		}
+	}
+	return requests, limits, unlimited
+}
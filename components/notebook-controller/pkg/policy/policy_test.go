@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func resources(cpu, memory string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if cpu != "" {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func TestCheck(t *testing.T) {
+	policy := &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.NotebookPolicySpec{
+			MaxResources:  resources("2", "4Gi"),
+			AllowedImages: []string{"gcr.io/kubeflow-images-public/*"},
+		},
+	}
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		want       []string
+	}{
+		{
+			name: "compliant",
+			containers: []corev1.Container{
+				{
+					Name:      "nb",
+					Image:     "gcr.io/kubeflow-images-public/tensorflow:1.12",
+					Resources: corev1.ResourceRequirements{Limits: resources("1", "2Gi")},
+				},
+				{
+					Name:      "sidecar",
+					Image:     "gcr.io/kubeflow-images-public/proxy:1",
+					Resources: corev1.ResourceRequirements{Limits: resources("1", "2Gi")},
+				},
+			},
+		},
+		{
+			name: "image not allowed",
+			containers: []corev1.Container{{
+				Name:      "nb",
+				Image:     "docker.io/jupyter/base-notebook",
+				Resources: corev1.ResourceRequirements{Limits: resources("1", "1Gi")},
+			}},
+			want: []string{
+				`spec.template.spec.containers[0].image: Forbidden: image "docker.io/jupyter/base-notebook" is not allowed by NotebookPolicy default`,
+			},
+		},
+		{
+			name: "too much cpu over all containers",
+			containers: []corev1.Container{
+				{
+					Name:      "nb",
+					Image:     "gcr.io/kubeflow-images-public/tensorflow:1.12",
+					Resources: corev1.ResourceRequirements{Limits: resources("2", "1Gi")},
+				},
+				{
+					Name:      "sidecar",
+					Image:     "gcr.io/kubeflow-images-public/proxy:1",
+					Resources: corev1.ResourceRequirements{Requests: resources("500m", ""), Limits: resources("1", "1Gi")},
+				},
+			},
+			want: []string{
+				"spec.template.spec.containers: Forbidden: the cpu requests 2500m exceed the maximum 2 of NotebookPolicy default",
+				"spec.template.spec.containers: Forbidden: the cpu limits 3 exceed the maximum 2 of NotebookPolicy default",
+			},
+		},
+		{
+			name: "no memory limit",
+			containers: []corev1.Container{{
+				Name:      "nb",
+				Image:     "gcr.io/kubeflow-images-public/tensorflow:1.12",
+				Resources: corev1.ResourceRequirements{Limits: resources("1", "")},
+			}},
+			want: []string{
+				"spec.template.spec.containers: Forbidden: all containers must set a memory limit, as NotebookPolicy default limits it",
+			},
+		},
+	}
+	for _, test := range tests {
+		errs := Check(policy, &corev1.PodSpec{Containers: test.containers})
+		if len(errs) != len(test.want) {
+			t.Errorf("%v: violations = %v; want %v", test.name, errs, test.want)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != test.want[i] {
+				t.Errorf("%v: violation = %q; want %q", test.name, err.Error(), test.want[i])
+			}
+		}
+	}
+}
+
+func TestCheckCount(t *testing.T) {
+	max := int32(2)
+	policy := &v1alpha1.NotebookPolicy{Spec: v1alpha1.NotebookPolicySpec{MaxNotebooks: &max}}
+	for index, want := range []bool{false, false, true} {
+		if got := CheckCount(policy, index) != nil; got != want {
+			t.Errorf("CheckCount(%v) violated = %v; want %v", index, got, want)
+		}
+	}
+	if err := CheckCount(&v1alpha1.NotebookPolicy{}, 100); err != nil {
+		t.Errorf("CheckCount without a maximum = %v; want nil", err)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"strconv"
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Ambassador routes through Ambassador v0 Mappings, one per port, in the
+// Service annotations.
+type Ambassador struct{}
+
+// Name implements Provider.
+func (Ambassador) Name() string {
+	return "ambassador"
+}
+
+// ServiceAnnotations implements Provider.
+func (Ambassador) ServiceAnnotations(nb *v1alpha1.Notebook, ports []Port) map[string]string {
+	var mappings []string
+	for _, p := range ports {
+		name := "notebook_" + nb.Namespace + "_" + nb.Name + "_mapping"
+		if p.Name != NotebookPortName {
+			name = "notebook_" + nb.Namespace + "_" + nb.Name + "_" + p.Name + "_mapping"
+		}
+		mappings = append(mappings, strings.Join(
+			[]string{
+				"---",
+				"apiVersion: ambassador/v0",
+				"kind:  Mapping",
+				"name: " + name,
+				"prefix: " + p.Prefix,
+				"rewrite: " + p.Rewrite,
+				"timeout_ms: 300000",
+				"service: " + nb.Name + "." + nb.Namespace + ":" + strconv.Itoa(p.ServicePort),
+				"use_websocket: true",
+			}, "\n"))
+	}
+	return map[string]string{
+		"getambassador.io/config": strings.Join(mappings, "\n"),
+	}
+}
+
+// Routes implements Provider. The Mappings live on the Service.
+func (Ambassador) Routes(nb *v1alpha1.Notebook, ports []Port) ([]*unstructured.Unstructured, error) {
+	return nil, nil
+}
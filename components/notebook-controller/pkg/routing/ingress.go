@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"regexp"
+	"strings"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var ingressKind = extv1beta1.SchemeGroupVersion.WithKind("Ingress")
+
+// Ingress routes through Kubernetes Ingresses. The prefix is rewritten with
+// the annotations understood by ingress-nginx 0.22 and later, which apply to
+// all paths of an Ingress, so there is one Ingress per port. The path is a
+// regular expression capturing what follows the prefix, which is appended to
+// the rewrite target, so that the paths under the prefix keep their suffix.
+type Ingress struct {
+	// Class is the ingress class to use, if any.
+	Class string
+}
+
+// Name implements Provider.
+func (*Ingress) Name() string {
+	return "ingress"
+}
+
+// ServiceAnnotations implements Provider.
+func (*Ingress) ServiceAnnotations(nb *v1alpha1.Notebook, ports []Port) map[string]string {
+	return nil
+}
+
+// Routes implements Provider. The Ingresses are named with RouteName.
+func (i *Ingress) Routes(nb *v1alpha1.Notebook, ports []Port) ([]*unstructured.Unstructured, error) {
+	var routes []*unstructured.Unstructured
+	for _, p := range ports {
+		route, err := i.route(nb, p)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func (i *Ingress) route(nb *v1alpha1.Notebook, p Port) (*unstructured.Unstructured, error) {
+	annotations := map[string]string{
+		"nginx.ingress.kubernetes.io/use-regex":      "true",
+		"nginx.ingress.kubernetes.io/rewrite-target": strings.TrimSuffix(p.Rewrite, "/") + "/$2",
+	}
+	if i.Class != "" {
+		annotations["kubernetes.io/ingress.class"] = i.Class
+	}
+	ing := &extv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        RouteName(nb, p.Name),
+			Namespace:   nb.Namespace,
+			Annotations: annotations,
+		},
+		Spec: extv1beta1.IngressSpec{
+			Rules: []extv1beta1.IngressRule{
+				{
+					IngressRuleValue: extv1beta1.IngressRuleValue{
+						HTTP: &extv1beta1.HTTPIngressRuleValue{
+							Paths: []extv1beta1.HTTPIngressPath{
+								{
+									Path: regexp.QuoteMeta(p.Prefix) + "(/|$)(.*)",
+									Backend: extv1beta1.IngressBackend{
+										ServiceName: nb.Name,
+										ServicePort: intstr.FromInt(p.ServicePort),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return toUnstructured(ing, ingressKind)
+}
+
+// toUnstructured converts a typed object, so that all routing objects can be
+// reconciled the same way.
+func toUnstructured(obj runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
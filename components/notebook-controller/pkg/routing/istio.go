@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The Istio types are not vendored, so VirtualServices are handled as unstructured objects.
+var virtualServiceKind = schema.GroupVersionKind{
+	Group:   "networking.istio.io",
+	Version: "v1alpha3",
+	Kind:    "VirtualService",
+}
+
+// Istio routes through an Istio VirtualService bound to a gateway, with an
+// HTTP route per port.
+type Istio struct {
+	// Gateway is the <namespace>/<name> of the Istio gateway to bind to.
+	Gateway string
+}
+
+// Name implements Provider.
+func (*Istio) Name() string {
+	return "istio"
+}
+
+// ServiceAnnotations implements Provider.
+func (*Istio) ServiceAnnotations(nb *v1alpha1.Notebook, ports []Port) map[string]string {
+	return nil
+}
+
+// Routes implements Provider. The routes of the exposed ports come first, as
+// their prefixes are under the one of the notebook server and Istio uses the
+// first matching route.
+func (i *Istio) Routes(nb *v1alpha1.Notebook, ports []Port) ([]*unstructured.Unstructured, error) {
+	var http []interface{}
+	for j := len(ports) - 1; j >= 0; j-- {
+		p := ports[j]
+		http = append(http, map[string]interface{}{
+			"match": []interface{}{
+				map[string]interface{}{
+					"uri": map[string]interface{}{
+						"prefix": p.Prefix,
+					},
+				},
+			},
+			"rewrite": map[string]interface{}{
+				"uri": p.Rewrite,
+			},
+			"route": []interface{}{
+				map[string]interface{}{
+					"destination": map[string]interface{}{
+						"host": ServiceHost(nb),
+						"port": map[string]interface{}{
+							"number": int64(p.ServicePort),
+						},
+					},
+				},
+			},
+			"timeout": "300s",
+		})
+	}
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(virtualServiceKind)
+	vs.SetName(nb.Name)
+	vs.SetNamespace(nb.Namespace)
+	vs.Object["spec"] = map[string]interface{}{
+		"hosts":    []interface{}{"*"},
+		"gateways": []interface{}{i.Gateway},
+		"http":     http,
+	}
+	return []*unstructured.Unstructured{vs}, nil
+}
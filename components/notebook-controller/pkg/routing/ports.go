@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"fmt"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NotebookPortName is the name of the port of the notebook server.
+const NotebookPortName = "notebook"
+
+// Port is a port of the notebook Service that external requests are routed to.
+type Port struct {
+	// Name is NotebookPortName for the notebook server, or the name of the
+	// exposed container port.
+	Name string
+	// ServicePort is the port of the notebook Service, which forwards to the
+	// container port TargetPort.
+	ServicePort int
+	TargetPort  int
+	// Prefix is the external path of the port. Requests under it are rewritten to Rewrite.
+	Prefix  string
+	Rewrite string
+}
+
+// ServicePortName is the name of the port of the notebook Service. The http
+// prefix tells Istio the protocol of the port.
+func (p Port) ServicePortName() string {
+	return "http-" + p.Name
+}
+
+// URL is the URL users open, the prefix with a trailing slash.
+func (p Port) URL() string {
+	return p.Prefix + "/"
+}
+
+// PortPrefix is the external path under which the exposed port is served.
+func PortPrefix(nb *v1alpha1.Notebook, name string) string {
+	return Prefix(nb) + "/ports/" + name
+}
+
+// NotebookContainerPort returns the port the Jupyter server of the notebook
+// running podSpec listens on: the first port of the notebook container.
+func NotebookContainerPort(podSpec *corev1.PodSpec) int {
+	if len(podSpec.Containers) > 0 && len(podSpec.Containers[0].Ports) > 0 {
+		return int(podSpec.Containers[0].Ports[0].ContainerPort)
+	}
+	return v1alpha1.DefaultContainerPort
+}
+
+// Ports returns the ports of the notebook running podSpec that are routed to:
+// the notebook server, and then the exposed container ports in the order of
+// the spec. Requests to an exposed port are rewritten to the root path, and it
+// is published on the Service under its container port number. It also returns
+// the problems of the exposed ports, which are skipped.
+func Ports(nb *v1alpha1.Notebook, podSpec *corev1.PodSpec) ([]Port, []string) {
+	ports := []Port{{
+		Name:        NotebookPortName,
+		ServicePort: ServicePort,
+		TargetPort:  NotebookContainerPort(podSpec),
+		Prefix:      Prefix(nb),
+		Rewrite:     BaseURL(nb),
+	}}
+
+	containerPorts := map[string]corev1.ContainerPort{}
+	for _, c := range podSpec.Containers {
+		for _, p := range c.Ports {
+			if p.Name != "" {
+				containerPorts[p.Name] = p
+			}
+		}
+	}
+	var problems []string
+	published := map[string]bool{NotebookPortName: true}
+	servicePorts := map[int]bool{ServicePort: true}
+	for _, name := range nb.Spec.ExposedPorts {
+		p, ok := containerPorts[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("the notebook has no container port named %v", name))
+			continue
+		case published[name]:
+			problems = append(problems, fmt.Sprintf("the port %v is already published", name))
+			continue
+		case p.Protocol != "" && p.Protocol != corev1.ProtocolTCP:
+			problems = append(problems, fmt.Sprintf("the port %v is not a TCP port", name))
+			continue
+		case servicePorts[int(p.ContainerPort)]:
+			problems = append(problems, fmt.Sprintf("the port number %v of %v is already used by the notebook Service", p.ContainerPort, name))
+			continue
+		}
+		published[name] = true
+		servicePorts[int(p.ContainerPort)] = true
+		ports = append(ports, Port{
+			Name:        name,
+			ServicePort: int(p.ContainerPort),
+			TargetPort:  int(p.ContainerPort),
+			Prefix:      PortPrefix(nb, name),
+			Rewrite:     "/",
+		})
+	}
+	return ports, problems
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPorts(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "nb", Ports: []corev1.ContainerPort{
+				{Name: "notebook-port", ContainerPort: 8888},
+				{Name: "tensorboard", ContainerPort: 6006},
+				{Name: "web", ContainerPort: 80},
+			}},
+			{Name: "sidecar", Ports: []corev1.ContainerPort{
+				{Name: "dashboard", ContainerPort: 8050},
+				{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolUDP},
+			}},
+		},
+	}
+	nb := &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"},
+		Spec: v1alpha1.NotebookSpec{
+			ExposedPorts: []string{"tensorboard", "dashboard", "missing", "web", "metrics", "tensorboard"},
+		},
+	}
+
+	ports, problems := Ports(nb, podSpec)
+	want := []Port{
+		{Name: "notebook", ServicePort: 80, TargetPort: 8888, Prefix: "/notebook/test/nb", Rewrite: "/test/nb"},
+		{Name: "tensorboard", ServicePort: 6006, TargetPort: 6006, Prefix: "/notebook/test/nb/ports/tensorboard", Rewrite: "/"},
+		{Name: "dashboard", ServicePort: 8050, TargetPort: 8050, Prefix: "/notebook/test/nb/ports/dashboard", Rewrite: "/"},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("Ports = %+v; want %+v", ports, want)
+	}
+	wantProblems := []string{
+		"the notebook has no container port named missing",
+		"the port number 80 of web is already used by the notebook Service",
+		"the port metrics is not a TCP port",
+		"the port tensorboard is already published",
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("problems = %q; want %q", problems, wantProblems)
+	}
+}
+
+func TestPortsDefault(t *testing.T) {
+	nb := &v1alpha1.Notebook{ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"}}
+	ports, problems := Ports(nb, &corev1.PodSpec{Containers: []corev1.Container{{Name: "nb"}}})
+	if len(ports) != 1 || ports[0].TargetPort != v1alpha1.DefaultContainerPort || len(problems) != 0 {
+		t.Errorf("Ports = %+v, %v; want the notebook server on the default port", ports, problems)
+	}
+}
+
+func TestIngressRoutes(t *testing.T) {
+	nb := &v1alpha1.Notebook{ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"}}
+	ports := []Port{
+		{Name: "notebook", ServicePort: 80, TargetPort: 8888, Prefix: "/notebook/test/nb", Rewrite: "/test/nb"},
+		{Name: "tensorboard", ServicePort: 6006, TargetPort: 6006, Prefix: "/notebook/test/nb/ports/tensorboard", Rewrite: "/"},
+	}
+	routes, err := (&Ingress{}).Routes(nb, ports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("got %v Ingresses; want 2", len(routes))
+	}
+	tests := []struct{ name, path, rewrite string }{
+		{"nb", "/notebook/test/nb(/|$)(.*)", "/test/nb/$2"},
+		{"nb-tensorboard", "/notebook/test/nb/ports/tensorboard(/|$)(.*)", "/$2"},
+	}
+	for i, want := range tests {
+		ing := toIngress(t, routes[i])
+		rewrite := ing.Annotations["nginx.ingress.kubernetes.io/rewrite-target"]
+		path := ing.Spec.Rules[0].HTTP.Paths[0].Path
+		if ing.Name != want.name || path != want.path || rewrite != want.rewrite ||
+			ing.Annotations["nginx.ingress.kubernetes.io/use-regex"] != "true" {
+			t.Errorf("Ingress %v of path %v rewrites to %v with annotations %v; want %v of path %v rewriting to %v with a regex",
+				ing.Name, path, rewrite, ing.Annotations, want.name, want.path, want.rewrite)
+		}
+	}
+
+	// The rewrite keeps the suffix of the path, as ingress-nginx applies it.
+	requests := []struct{ path, want string }{
+		{"/notebook/test/nb", "/test/nb/"},
+		{"/notebook/test/nb/", "/test/nb/"},
+		{"/notebook/test/nb/api/kernels", "/test/nb/api/kernels"},
+		{"/notebook/test/nb/static/style.css", "/test/nb/static/style.css"},
+		{"/notebook/test/nb2/api", ""},
+	}
+	for _, req := range requests {
+		if got := rewriteIngressPath(t, routes[0], req.path); got != req.want {
+			t.Errorf("request %v rewritten to %q; want %q", req.path, got, req.want)
+		}
+	}
+}
+
+func toIngress(t *testing.T, u *unstructured.Unstructured) *extv1beta1.Ingress {
+	t.Helper()
+	ing := &extv1beta1.Ingress{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, ing); err != nil {
+		t.Fatal(err)
+	}
+	return ing
+}
+
+// rewriteIngressPath returns the path the request path is rewritten to by the
+// Ingress, as by ingress-nginx, or "" if it doesn't match.
+func rewriteIngressPath(t *testing.T, u *unstructured.Unstructured, path string) string {
+	ing := toIngress(t, u)
+	re := regexp.MustCompile("^" + ing.Spec.Rules[0].HTTP.Paths[0].Path)
+	match := re.FindStringSubmatchIndex(path)
+	if match == nil {
+		return ""
+	}
+	// nginx substitutes $2 like ${2} in Go templates.
+	template := strings.Replace(ing.Annotations["nginx.ingress.kubernetes.io/rewrite-target"], "$2", "${2}", -1)
+	return string(re.ExpandString(nil, template, path, match))
+}
+
+func TestProviderNames(t *testing.T) {
+	var names []string
+	for _, p := range []Provider{Ambassador{}, &Istio{}, &Ingress{}} {
+		names = append(names, p.Name())
+	}
+	if !reflect.DeepEqual(names, ProviderNames) {
+		t.Errorf("ProviderNames = %v; want the names of the providers %v", ProviderNames, names)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package routing generates the objects that route external traffic to notebooks.
+package routing
+
+import (
+	"fmt"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProviderAnnotation selects the routing provider of a single notebook,
+// overriding the default of the controller.
+const ProviderAnnotation = "notebooks.kubeflow.org/routing-provider"
+
+// ProviderNames are the names of the providers the controller supports, the
+// valid values of ProviderAnnotation.
+var ProviderNames = []string{"ambassador", "istio", "ingress"}
+
+// ServicePort is the port of the notebook Service that the routes of the
+// notebook server point to.
+const ServicePort = 80
+
+// Prefix is the external path under which the notebook is served.
+func Prefix(nb *v1alpha1.Notebook) string {
+	return "/notebook/" + nb.Namespace + "/" + nb.Name
+}
+
+// BaseURL is the path the Jupyter server of the notebook is started with.
+// Requests under Prefix are rewritten to it.
+func BaseURL(nb *v1alpha1.Notebook) string {
+	return "/" + nb.Namespace + "/" + nb.Name
+}
+
+// ServiceHost is the cluster DNS name of the notebook Service.
+func ServiceHost(nb *v1alpha1.Notebook) string {
+	return nb.Name + "." + nb.Namespace + ".svc.cluster.local"
+}
+
+// RouteName is the name of the routing objects of a port of the notebook that
+// are not shared with the other ports: the name of the notebook for the
+// notebook server, and <notebook>-<port> for an exposed port.
+func RouteName(nb *v1alpha1.Notebook, portName string) string {
+	if portName == NotebookPortName {
+		return nb.Name
+	}
+	return nb.Name + "-" + portName
+}
+
+// Provider configures how requests under the prefixes of the notebook ports
+// reach its Service.
+type Provider interface {
+	// Name identifies the provider in flags and annotations.
+	Name() string
+	// ServiceAnnotations returns the annotations the provider needs on the
+	// notebook Service to route to the ports.
+	ServiceAnnotations(nb *v1alpha1.Notebook, ports []Port) map[string]string
+	// Routes returns the routing objects of the notebook, or none if the
+	// provider only needs the Service annotations.
+	Routes(nb *v1alpha1.Notebook, ports []Port) ([]*unstructured.Unstructured, error)
+}
+
+// RouteKinds are the kinds of all routing objects created by the providers.
+// Routing objects of the providers not selected for a notebook are deleted.
+var RouteKinds = []schema.GroupVersionKind{
+	virtualServiceKind,
+	ingressKind,
+}
+
+// Registry holds the available providers by name.
+type Registry struct {
+	providers map[string]Provider
+	// Default is the name of the provider used for notebooks without annotation.
+	Default string
+}
+
+// NewRegistry returns a Registry of the given providers.
+func NewRegistry(defaultName string, providers ...Provider) *Registry {
+	reg := &Registry{providers: map[string]Provider{}, Default: defaultName}
+	for _, p := range providers {
+		reg.providers[p.Name()] = p
+	}
+	return reg
+}
+
+// ProviderFor returns the provider selected for the notebook.
+func (reg *Registry) ProviderFor(nb *v1alpha1.Notebook) (Provider, error) {
+	name := reg.Default
+	if v, ok := nb.Annotations[ProviderAnnotation]; ok {
+		name = v
+	}
+	p, ok := reg.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown routing provider %q", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedule parses the cron expressions of notebook schedules, and
+// tells whether a notebook runs at a given time.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five standard fields: minute,
+// hour, day of month, month and day of week. Fields are *, numbers, ranges
+// like 1-5, lists like 1,3,5 and steps like */15 or 8-18/2. Months and days of
+// week may also be given by their first three letters, e.g. JAN or MON-FRI.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true if the day fields are *. If neither is,
+	// a day matches if it matches either field, like in cron.
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	min, max int
+	names    []string
+}
+
+var (
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// Sunday is 0 or 7.
+	dowBounds = bounds{0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// ParseCron parses a cron expression, e.g. "0 8 * * MON-FRI".
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, not %v", expr, len(fields))
+	}
+	c := &Cron{}
+	var err error
+	if c.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField returns the bit set of the values of a field.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], s
+		}
+		low, high := b.min, b.max
+		if rangePart != "*" {
+			var err error
+			bound := strings.SplitN(rangePart, "-", 2)
+			if low, err = parseValue(bound[0], b); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bound) == 2 {
+				if high, err = parseValue(bound[1], b); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// 5/10 means from 5 to the maximum, every 10.
+				high = b.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or a name of a field.
+func parseValue(s string, b bounds) (int, error) {
+	for i, name := range b.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("%q is not a value from %v to %v", s, b.min, b.max)
+	}
+	return v, nil
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+// dayMatches returns true if the day of t matches the day of month and day of week fields.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t that matches the expression, in the
+// location of t. It returns the zero time if there is none within five years,
+// e.g. for February 30.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+	// Advance field by field, from the month to the minute, and start over
+	// when a field wraps around.
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for !has(c.month, int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for !has(c.hour, t.Hour()) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for !has(c.minute, t.Minute()) {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+// Window is a recurring window in which a notebook runs. It opens at the times
+// of the start expression and closes at the times of the stop expression.
+type Window struct {
+	start, stop *Cron
+	loc         *time.Location
+}
+
+// NewWindow returns the window between the start and stop cron expressions in
+// the time zone, an IANA name like Europe/Berlin. The time zone defaults to UTC.
+func NewWindow(start, stop, timeZone string) (*Window, error) {
+	w := &Window{loc: time.UTC}
+	var err error
+	if w.start, err = ParseCron(start); err != nil {
+		return nil, fmt.Errorf("start: %v", err)
+	}
+	if w.stop, err = ParseCron(stop); err != nil {
+		return nil, fmt.Errorf("stop: %v", err)
+	}
+	if timeZone != "" {
+		if w.loc, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("time zone: %v", err)
+		}
+	}
+	now := time.Now()
+	if w.start.Next(now).IsZero() || w.stop.Next(now).IsZero() {
+		return nil, fmt.Errorf("the start and stop expressions must match some time")
+	}
+	return w, nil
+}
+
+// At returns whether the window is open at t, and when it next opens or closes.
+// The window is open if it next closes before it opens again. If the start and
+// stop expressions match the same time, the stop wins.
+func (w *Window) At(t time.Time) (open bool, next time.Time) {
+	t = t.In(w.loc)
+	nextStart, nextStop := w.start.Next(t), w.stop.Next(t)
+	if nextStop.Before(nextStart) {
+		return true, nextStop
+	}
+	return false, nextStart
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, layout string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"* * * * *", "2019-02-01T10:00:30Z", "2019-02-01T10:01:00Z"},
+		{"0 8 * * MON-FRI", "2019-02-01T10:00:00Z", "2019-02-04T08:00:00Z"},
+		{"0 8 * * 1-5", "2019-02-04T07:59:00Z", "2019-02-04T08:00:00Z"},
+		{"*/15 * * * *", "2019-02-01T10:07:00Z", "2019-02-01T10:15:00Z"},
+		{"30 9-17/4 * * *", "2019-02-01T14:00:00Z", "2019-02-01T17:30:00Z"},
+		{"0 0 1 jan,jul *", "2019-02-01T00:00:00Z", "2019-07-01T00:00:00Z"},
+		{"0 0 * * 7", "2019-02-01T00:00:00Z", "2019-02-03T00:00:00Z"},
+		// Either day field matches when both are set.
+		{"0 0 13 * 5", "2019-02-01T00:00:00Z", "2019-02-08T00:00:00Z"},
+		{"0 0 29 2 *", "2019-03-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"0 0 30 2 *", "2019-03-01T00:00:00Z", ""},
+	}
+	for _, test := range tests {
+		c, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", test.expr, err)
+			continue
+		}
+		got := c.Next(mustParse(t, test.from))
+		if test.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%q: Next(%v) = %v; want none", test.expr, test.from, got)
+			}
+			continue
+		}
+		if want := mustParse(t, test.want); !got.Equal(want) {
+			t.Errorf("%q: Next(%v) = %v; want %v", test.expr, test.from, got, want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * FOO *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded; want an error", expr)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w, err := NewWindow("0 8 * * MON-FRI", "0 20 * * MON-FRI", "Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		at       string
+		wantOpen bool
+		wantNext string
+	}{
+		// Friday 09:00 in Berlin, UTC+1 in winter.
+		{"2019-02-01T08:00:00Z", true, "2019-02-01T19:00:00Z"},
+		// Friday 21:00, closed over the weekend.
+		{"2019-02-01T20:00:00Z", false, "2019-02-04T07:00:00Z"},
+		{"2019-02-02T12:00:00Z", false, "2019-02-04T07:00:00Z"},
+		// Opens at 08:00 sharp, UTC+2 in summer.
+		{"2019-07-01T06:00:00Z", true, "2019-07-01T18:00:00Z"},
+		{"2019-07-01T05:59:00Z", false, "2019-07-01T06:00:00Z"},
+	}
+	for _, test := range tests {
+		open, next := w.At(mustParse(t, test.at))
+		if open != test.wantOpen || !next.Equal(mustParse(t, test.wantNext)) {
+			t.Errorf("At(%v) = %v, %v; want %v, %v", test.at, open, next.UTC(), test.wantOpen, test.wantNext)
+		}
+	}
+
+	if _, err := NewWindow("0 8 * * *", "0 20 * * *", "Mars/Olympus"); err == nil {
+		t.Error("NewWindow with an unknown time zone succeeded; want an error")
+	}
+	if _, err := NewWindow("0 8 30 2 *", "0 20 * * *", ""); err == nil {
+		t.Error("NewWindow with a start that never matches succeeded; want an error")
+	}
+}
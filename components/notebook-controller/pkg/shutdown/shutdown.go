@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shutdown lets the manager wait for running reconciles before the
+// process exits. The manager of controller-runtime returns as soon as it is
+// stopped, while the workers of the controllers may still be reconciling.
+package shutdown
+
+import (
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Tracker counts the running reconciles of the reconcilers it wraps.
+type Tracker struct {
+	mu       sync.Mutex
+	running  int
+	draining bool
+	// idle is closed when the last running reconcile returns while draining.
+	idle chan struct{}
+}
+
+// Default is the Tracker of the controllers of the manager.
+var Default = &Tracker{}
+
+// Track wraps r so that the default Tracker waits for its reconciles.
+func Track(r reconcile.Reconciler) reconcile.Reconciler {
+	return Default.Track(r)
+}
+
+// Drain waits for the reconciles tracked by the default Tracker.
+func Drain(timeout time.Duration) bool {
+	return Default.Drain(timeout)
+}
+
+// Track wraps r so that Drain waits for its reconciles.
+func (t *Tracker) Track(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		if !t.start() {
+			// The request stays in the queue of the controller, and is
+			// reconciled by the next leader.
+			return reconcile.Result{Requeue: true}, nil
+		}
+		defer t.done()
+		return r.Reconcile(request)
+	})
+}
+
+// Drain stops new reconciles from starting and waits up to timeout for the
+// running ones to return. It returns false if they didn't return in time.
+func (t *Tracker) Drain(timeout time.Duration) bool {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if t.running == 0 {
+			close(t.idle)
+		}
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *Tracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.running++
+	return true
+}
+
+func (t *Tracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	if t.draining && t.running == 0 {
+		close(t.idle)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown
+
+import (
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDrain(t *testing.T) {
+	tracker := &Tracker{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	r := tracker.Track(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		calls++
+		close(started)
+		<-release
+		return reconcile.Result{}, nil
+	}))
+
+	go r.Reconcile(reconcile.Request{})
+	<-started
+	if tracker.Drain(10 * time.Millisecond) {
+		t.Errorf("Drain returned true while a reconcile is running")
+	}
+	// New reconciles are requeued without running.
+	if result, err := r.Reconcile(reconcile.Request{}); err != nil || !result.Requeue {
+		t.Errorf("Reconcile while draining = %+v, %v; want a requeue", result, err)
+	}
+	close(release)
+	if !tracker.Drain(time.Second) {
+		t.Errorf("Drain returned false after the reconcile returned")
+	}
+	if calls != 1 {
+		t.Errorf("reconciler called %v times; want 1", calls)
+	}
+}
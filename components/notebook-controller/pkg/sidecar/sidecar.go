@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sidecar injects the sidecars selected for a notebook, e.g. git-sync,
+// credential refreshers or log shippers, into its Pod. The sidecars are defined
+// in a ConfigMap, each under its name.
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Annotation on a notebook lists the names of the sidecars to inject into
+	// it, separated by commas.
+	Annotation = "notebooks.kubeflow.org/sidecars"
+	// NamespaceLabelPrefix followed by the name of a sidecar is the label that,
+	// set to "enabled" on a namespace, injects the sidecar into all its notebooks.
+	NamespaceLabelPrefix = "sidecars.notebooks.kubeflow.org/"
+)
+
+// Sidecar is the definition of a sidecar in the ConfigMap.
+type Sidecar struct {
+	// Name is the key of the definition in the ConfigMap.
+	Name string `json:"-"`
+	// Order of the sidecar among the injected ones. Sidecars of the same order
+	// are injected by name.
+	Order int `json:"order,omitempty"`
+	// InitContainers are added after the init containers of the notebook.
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+	// Containers are added after the containers of the notebook.
+	Containers []corev1.Container `json:"containers,omitempty"`
+	// Volumes are added to the Pod.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are added to the notebook container, e.g. to share a volume
+	// with the sidecar.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// Parse returns the sidecars defined in the ConfigMap, in injection order, and
+// the errors of the definitions that are invalid, by name. Invalid definitions
+// are left out, so they only break the notebooks that select them.
+func Parse(cm *corev1.ConfigMap) ([]Sidecar, map[string]error) {
+	var sidecars []Sidecar
+	invalid := map[string]error{}
+	for name, data := range cm.Data {
+		s := Sidecar{}
+		if err := yaml.Unmarshal([]byte(data), &s); err != nil {
+			invalid[name] = fmt.Errorf("invalid sidecar %v in ConfigMap %v/%v: %v", name, cm.Namespace, cm.Name, err)
+			continue
+		}
+		s.Name = name
+		sidecars = append(sidecars, s)
+	}
+	sort.Slice(sidecars, func(i, j int) bool {
+		if sidecars[i].Order != sidecars[j].Order {
+			return sidecars[i].Order < sidecars[j].Order
+		}
+		return sidecars[i].Name < sidecars[j].Name
+	})
+	return sidecars, invalid
+}
+
+// Select returns the sidecars selected by the annotation of the notebook or the
+// labels of its namespace, in injection order, and the names of the selected
+// sidecars that aren't defined, sorted. The namespace may be nil.
+func Select(sidecars []Sidecar, nb metav1.Object, namespace *corev1.Namespace) ([]Sidecar, []string) {
+	names := map[string]bool{}
+	for _, name := range strings.Split(nb.GetAnnotations()[Annotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	if namespace != nil {
+		for k, v := range namespace.Labels {
+			if strings.HasPrefix(k, NamespaceLabelPrefix) && v == "enabled" {
+				names[strings.TrimPrefix(k, NamespaceLabelPrefix)] = true
+			}
+		}
+	}
+
+	var selected []Sidecar
+	for _, s := range sidecars {
+		if names[s.Name] {
+			selected = append(selected, s)
+			delete(names, s.Name)
+		}
+	}
+	var unknown []string
+	for name := range names {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	return selected, unknown
+}
+
+// Inject adds the sidecars to the PodSpec, whose first container is the notebook
+// container. Containers, volumes and mounts the PodSpec already has are kept, so
+// injecting a sidecar twice has no effect, and a notebook can override the
+// container of a sidecar with its own of the same name.
+func Inject(podSpec *corev1.PodSpec, sidecars []Sidecar) {
+	for _, s := range sidecars {
+		for _, c := range s.InitContainers {
+			if !hasContainer(podSpec.InitContainers, c.Name) {
+				podSpec.InitContainers = append(podSpec.InitContainers, *c.DeepCopy())
+			}
+		}
+		for _, c := range s.Containers {
+			if !hasContainer(podSpec.Containers, c.Name) {
+				podSpec.Containers = append(podSpec.Containers, *c.DeepCopy())
+			}
+		}
+		for _, v := range s.Volumes {
+			if !hasVolume(podSpec.Volumes, v.Name) {
+				podSpec.Volumes = append(podSpec.Volumes, *v.DeepCopy())
+			}
+		}
+		if len(podSpec.Containers) == 0 {
+			continue
+		}
+		notebook := &podSpec.Containers[0]
+		for _, m := range s.VolumeMounts {
+			if !hasVolumeMount(notebook.VolumeMounts, m) {
+				notebook.VolumeMounts = append(notebook.VolumeMounts, m)
+			}
+		}
+	}
+}
+
+func hasContainer(containers []corev1.Container, name string) bool {
+	for _, c := range containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVolumeMount returns true if the mount or its path is already used.
+func hasVolumeMount(mounts []corev1.VolumeMount, mount corev1.VolumeMount) bool {
+	for _, m := range mounts {
+		if (m.Name == mount.Name && m.SubPath == mount.SubPath) || m.MountPath == mount.MountPath {
+			return true
+		}
+	}
+	return false
+}
+
+// Injector injects the sidecars defined in a ConfigMap into notebooks.
+type Injector struct {
+	Client client.Client
+	// ConfigMap is the ConfigMap of the sidecar definitions. There are no
+	// sidecars if it doesn't exist.
+	ConfigMap types.NamespacedName
+}
+
+// NewInjector returns an Injector of the sidecars defined in the ConfigMap
+// <namespace>/<name>.
+func NewInjector(c client.Client, configMap string) (*Injector, error) {
+	parts := strings.Split(configMap, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid sidecar ConfigMap %q, want <namespace>/<name>", configMap)
+	}
+	return &Injector{
+		Client:    c,
+		ConfigMap: types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+	}, nil
+}
+
+// Inject adds the sidecars selected for the notebook to podSpec. It returns the
+// names of the selected sidecars that aren't defined, which are ignored. It
+// fails if the notebook selects a sidecar whose definition is invalid.
+func (i *Injector) Inject(ctx context.Context, nb metav1.Object, podSpec *corev1.PodSpec) ([]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := i.Client.Get(ctx, i.ConfigMap, cm); errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{}
+	} else if err != nil {
+		return nil, err
+	}
+	sidecars, invalid := Parse(cm)
+	namespace := &corev1.Namespace{}
+	if err := i.Client.Get(ctx, types.NamespacedName{Name: nb.GetNamespace()}, namespace); errors.IsNotFound(err) {
+		namespace = nil
+	} else if err != nil {
+		return nil, err
+	}
+	selected, unknown := Select(sidecars, nb, namespace)
+	for _, name := range unknown {
+		if err := invalid[name]; err != nil {
+			return nil, err
+		}
+	}
+	Inject(podSpec, selected)
+	return unknown, nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testConfigMap = &corev1.ConfigMap{
+	ObjectMeta: metav1.ObjectMeta{Name: "notebook-sidecars", Namespace: "kubeflow"},
+	Data: map[string]string{
+		"log-shipper": `
+order: 10
+containers:
+- name: fluent-bit
+  image: fluent/fluent-bit:1.0
+  volumeMounts:
+  - name: logs
+    mountPath: /logs
+volumes:
+- name: logs
+  emptyDir: {}
+volumeMounts:
+- name: logs
+  mountPath: /home/jovyan/logs
+`,
+		"git-sync": `
+initContainers:
+- name: git-clone
+  image: k8s.gcr.io/git-sync:v3.1.1
+containers:
+- name: git-sync
+  image: k8s.gcr.io/git-sync:v3.1.1
+`,
+		"credentials": `
+containers:
+- name: credentials
+  image: gcr.io/kubeflow/credentials:1
+`,
+	},
+}
+
+func names(sidecars []Sidecar) []string {
+	var names []string
+	for _, s := range sidecars {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestParse(t *testing.T) {
+	sidecars, invalid := Parse(testConfigMap)
+	if len(invalid) > 0 {
+		t.Fatal(invalid)
+	}
+	// Sidecars are ordered by order, then name.
+	want := []string{"credentials", "git-sync", "log-shipper"}
+	if got := names(sidecars); !reflect.DeepEqual(got, want) {
+		t.Errorf("sidecars = %v; want %v", got, want)
+	}
+	if c := sidecars[1].InitContainers; len(c) != 1 || c[0].Name != "git-clone" {
+		t.Errorf("init containers of git-sync = %+v; want git-clone", c)
+	}
+
+	// An invalid definition is reported and left out.
+	cm := &corev1.ConfigMap{Data: map[string]string{"broken": "containers: {", "credentials": testConfigMap.Data["credentials"]}}
+	sidecars, invalid = Parse(cm)
+	if got := names(sidecars); !reflect.DeepEqual(got, []string{"credentials"}) {
+		t.Errorf("sidecars with an invalid definition = %v; want credentials", got)
+	}
+	if len(invalid) != 1 || invalid["broken"] == nil {
+		t.Errorf("invalid definitions = %v; want broken", invalid)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	sidecars, invalid := Parse(testConfigMap)
+	if len(invalid) > 0 {
+		t.Fatal(invalid)
+	}
+	tests := []struct {
+		name        string
+		annotation  string
+		labels      map[string]string
+		want        []string
+		wantUnknown []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:       "annotation",
+			annotation: "log-shipper, git-sync",
+			want:       []string{"git-sync", "log-shipper"},
+		},
+		{
+			name:       "annotation and namespace",
+			annotation: "git-sync",
+			labels: map[string]string{
+				NamespaceLabelPrefix + "log-shipper": "enabled",
+				NamespaceLabelPrefix + "git-sync":    "enabled",
+				NamespaceLabelPrefix + "credentials": "disabled",
+			},
+			want: []string{"git-sync", "log-shipper"},
+		},
+		{
+			name:        "unknown",
+			annotation:  "git-sync,vscode",
+			labels:      map[string]string{NamespaceLabelPrefix + "backup": "enabled"},
+			want:        []string{"git-sync"},
+			wantUnknown: []string{"backup", "vscode"},
+		},
+	}
+	for _, test := range tests {
+		nb := &metav1.ObjectMeta{Annotations: map[string]string{Annotation: test.annotation}}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Labels: test.labels}}
+		selected, unknown := Select(sidecars, nb, ns)
+		if got := names(selected); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: selected %v; want %v", test.name, got, test.want)
+		}
+		if !reflect.DeepEqual(unknown, test.wantUnknown) {
+			t.Errorf("%v: unknown %v; want %v", test.name, unknown, test.wantUnknown)
+		}
+	}
+}
+
+func TestInject(t *testing.T) {
+	sidecars, invalid := Parse(testConfigMap)
+	if len(invalid) > 0 {
+		t.Fatal(invalid)
+	}
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "notebook", Image: "jupyter"},
+			// The notebook overrides the git-sync container.
+			{Name: "git-sync", Image: "k8s.gcr.io/git-sync:v3.0.0"},
+		},
+	}
+	Inject(podSpec, sidecars)
+
+	var containers []string
+	for _, c := range podSpec.Containers {
+		containers = append(containers, c.Name)
+	}
+	want := []string{"notebook", "git-sync", "credentials", "fluent-bit"}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("containers = %v; want %v", containers, want)
+	}
+	if image := podSpec.Containers[1].Image; image != "k8s.gcr.io/git-sync:v3.0.0" {
+		t.Errorf("image of git-sync = %v; want the one of the notebook", image)
+	}
+	if len(podSpec.InitContainers) != 1 || len(podSpec.Volumes) != 1 {
+		t.Errorf("init containers = %+v, volumes = %+v; want git-clone and logs", podSpec.InitContainers, podSpec.Volumes)
+	}
+	if mounts := podSpec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/home/jovyan/logs" {
+		t.Errorf("mounts of the notebook container = %+v; want the logs", mounts)
+	}
+
+	// Injecting again has no effect.
+	injected := podSpec.DeepCopy()
+	Inject(podSpec, sidecars)
+	if !reflect.DeepEqual(podSpec, injected) {
+		t.Errorf("second injection changed the PodSpec to %+v; want %+v", podSpec, injected)
+	}
+}
+
+func TestInjectorInvalidSidecar(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "notebook-sidecars", Namespace: "kubeflow"},
+		Data:       map[string]string{"broken": "containers: {", "credentials": testConfigMap.Data["credentials"]},
+	}
+	i := &Injector{
+		Client:    fake.NewFakeClient(cm),
+		ConfigMap: types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace},
+	}
+
+	// The notebooks that don't select the invalid sidecar get theirs.
+	nb := &metav1.ObjectMeta{Namespace: "test", Annotations: map[string]string{Annotation: "credentials"}}
+	podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "notebook"}}}
+	if _, err := i.Inject(context.TODO(), nb, podSpec); err != nil {
+		t.Fatalf("Inject of a valid sidecar: %v", err)
+	}
+	if len(podSpec.Containers) != 2 || podSpec.Containers[1].Name != "credentials" {
+		t.Errorf("containers = %+v; want the credentials sidecar", podSpec.Containers)
+	}
+
+	// The notebooks that select it fail.
+	nb.Annotations[Annotation] = "credentials,broken"
+	_, err := i.Inject(context.TODO(), nb, &corev1.PodSpec{})
+	if err == nil || !strings.Contains(err.Error(), "invalid sidecar broken") {
+		t.Errorf("Inject of an invalid sidecar returned %v; want an error naming it", err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodTemplateHashAnnotation holds the hash of the PodSpec last written by the
+// controller. A changed hash means the desired PodSpec changed, including
+// fields that were removed from it.
+const PodTemplateHashAnnotation = "notebooks.kubeflow.org/pod-template-hash"
+
+// PodTemplateHash returns the hash of spec stored in PodTemplateHashAnnotation.
+func PodTemplateHash(spec *corev1.PodSpec) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		// A PodSpec always marshals; an empty hash just forces an update.
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum32())
+}
+
+// CopyStatefulSetFields copies the owned fields from one StatefulSet to another,
+// the replicas and those copied by copyOwnedPodTemplateFields. The selector is
+// immutable, and the update strategy is left to the user. It returns true if
+// to was changed.
+func CopyStatefulSetFields(from, to *appsv1.StatefulSet) bool {
+	requireUpdate := copyOwnedPodTemplateFields(&from.ObjectMeta, &to.ObjectMeta, &from.Spec.Template, &to.Spec.Template)
+	if from.Spec.Replicas != nil && (to.Spec.Replicas == nil || *from.Spec.Replicas != *to.Spec.Replicas) {
+		replicas := *from.Spec.Replicas
+		to.Spec.Replicas = &replicas
+		requireUpdate = true
+	}
+	return requireUpdate
+}
+
+// CopyDaemonSetFields copies the owned fields from one DaemonSet to another,
+// those copied by copyOwnedPodTemplateFields. It returns true if to was changed.
+func CopyDaemonSetFields(from, to *appsv1.DaemonSet) bool {
+	return copyOwnedPodTemplateFields(&from.ObjectMeta, &to.ObjectMeta, &from.Spec.Template, &to.Spec.Template)
+}
+
+// copyOwnedPodTemplateFields copies the owned fields of the metadata and of the
+// Pod template of a workload from one object to another. Only the labels and
+// annotations set in from are owned, so the ones added by others are kept. The
+// PodSpec is copied if from has another PodTemplateHashAnnotation, or if one of
+// the fields set in from has another value in to. Fields defaulted by the API
+// server don't count as changes. It returns true if to was changed.
+func copyOwnedPodTemplateFields(fromMeta, toMeta *metav1.ObjectMeta, from, to *corev1.PodTemplateSpec) bool {
+	requireUpdate := false
+	templateChanged := fromMeta.Annotations[PodTemplateHashAnnotation] != toMeta.Annotations[PodTemplateHashAnnotation]
+
+	if copyOwnedMap(fromMeta.Labels, &toMeta.Labels) {
+		requireUpdate = true
+	}
+	if copyOwnedMap(fromMeta.Annotations, &toMeta.Annotations) {
+		requireUpdate = true
+	}
+	if copyOwnedMap(from.Labels, &to.Labels) {
+		requireUpdate = true
+	}
+	if copyOwnedMap(from.Annotations, &to.Annotations) {
+		requireUpdate = true
+	}
+	if templateChanged || !ContainsOwnedFields(from.Spec, to.Spec) {
+		to.Spec = from.Spec
+		requireUpdate = true
+	}
+	return requireUpdate
+}
+
+// copyOwnedMap sets the entries of from in to, keeping the other entries of to.
+// It returns true if to was changed.
+func copyOwnedMap(from map[string]string, to *map[string]string) bool {
+	changed := false
+	for k, v := range from {
+		if cur, ok := (*to)[k]; ok && cur == v {
+			continue
+		}
+		if *to == nil {
+			*to = map[string]string{}
+		}
+		(*to)[k] = v
+		changed = true
+	}
+	return changed
+}
+
+// ContainsOwnedFields returns true if every field set in desired has the same
+// value in actual. Fields that are unset or zero in desired are not owned, e.g.
+// because the API server defaults them, and are ignored. Lists must have the
+// same length, and their items are compared the same way.
+func ContainsOwnedFields(desired, actual interface{}) bool {
+	d, err := toJSONValue(desired)
+	if err != nil {
+		return false
+	}
+	a, err := toJSONValue(actual)
+	if err != nil {
+		return false
+	}
+	return containsFields(d, a)
+}
+
+// toJSONValue converts obj to its JSON form of maps, lists and scalars.
+func toJSONValue(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+func containsFields(desired, actual interface{}) bool {
+	switch d := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		a, _ := actual.(map[string]interface{})
+		for k, v := range d {
+			if !containsFields(v, a[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, _ := actual.([]interface{})
+		if len(a) != len(d) {
+			return false
+		}
+		for i := range d {
+			if !containsFields(d[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		// A JSON scalar: a string, number or boolean.
+		if d == reflect.Zero(reflect.TypeOf(d)).Interface() {
+			return true
+		}
+		return reflect.DeepEqual(desired, actual)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDesiredStatefulSet(image string) *appsv1.StatefulSet {
+	replicas := int32(1)
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"statefulset": "nb"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"statefulset": "nb"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "nb",
+						Image: image,
+						Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+					}},
+				},
+			},
+		},
+	}
+	ss.Annotations = map[string]string{PodTemplateHashAnnotation: PodTemplateHash(&ss.Spec.Template.Spec)}
+	return ss
+}
+
+// defaulted returns ss with the fields set by the API server and other controllers.
+func defaulted(ss *appsv1.StatefulSet) *appsv1.StatefulSet {
+	ss = ss.DeepCopy()
+	revisionHistoryLimit := int32(10)
+	gracePeriod := int64(30)
+	ss.Annotations["other-controller"] = "value"
+	ss.Spec.RevisionHistoryLimit = &revisionHistoryLimit
+	ss.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	ss.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}
+	pod := &ss.Spec.Template.Spec
+	pod.RestartPolicy = corev1.RestartPolicyAlways
+	pod.DNSPolicy = corev1.DNSClusterFirst
+	pod.SchedulerName = corev1.DefaultSchedulerName
+	pod.SecurityContext = &corev1.PodSecurityContext{}
+	pod.TerminationGracePeriodSeconds = &gracePeriod
+	c := &pod.Containers[0]
+	c.ImagePullPolicy = corev1.PullIfNotPresent
+	c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	c.Ports[0].Protocol = corev1.ProtocolTCP
+	return ss
+}
+
+func TestCopyStatefulSetFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    *appsv1.StatefulSet
+		to      *appsv1.StatefulSet
+		changed bool
+		check   func(t *testing.T, ss *appsv1.StatefulSet)
+	}{
+		{
+			name: "defaulted fields",
+			from: newDesiredStatefulSet("jupyter:1"),
+			to:   defaulted(newDesiredStatefulSet("jupyter:1")),
+			check: func(t *testing.T, ss *appsv1.StatefulSet) {
+				if ss.Annotations["other-controller"] != "value" {
+					t.Errorf("annotation of another controller was removed")
+				}
+			},
+		},
+		{
+			name:    "changed image",
+			from:    newDesiredStatefulSet("jupyter:2"),
+			to:      defaulted(newDesiredStatefulSet("jupyter:1")),
+			changed: true,
+			check: func(t *testing.T, ss *appsv1.StatefulSet) {
+				if image := ss.Spec.Template.Spec.Containers[0].Image; image != "jupyter:2" {
+					t.Errorf("image = %v; want jupyter:2", image)
+				}
+			},
+		},
+		{
+			name: "owned field changed by someone else",
+			from: newDesiredStatefulSet("jupyter:1"),
+			to: func() *appsv1.StatefulSet {
+				ss := defaulted(newDesiredStatefulSet("jupyter:1"))
+				ss.Spec.Template.Spec.Containers[0].Image = "other"
+				return ss
+			}(),
+			changed: true,
+		},
+		{
+			name: "removed field",
+			from: newDesiredStatefulSet("jupyter:1"),
+			to: func() *appsv1.StatefulSet {
+				ss := newDesiredStatefulSet("jupyter:1")
+				ss.Spec.Template.Spec.NodeSelector = map[string]string{"gpu": "true"}
+				ss.Annotations[PodTemplateHashAnnotation] = PodTemplateHash(&ss.Spec.Template.Spec)
+				return defaulted(ss)
+			}(),
+			changed: true,
+			check: func(t *testing.T, ss *appsv1.StatefulSet) {
+				if ss.Spec.Template.Spec.NodeSelector != nil {
+					t.Errorf("node selector = %v; want it to be removed", ss.Spec.Template.Spec.NodeSelector)
+				}
+			},
+		},
+		{
+			name: "scaled",
+			from: newDesiredStatefulSet("jupyter:1"),
+			to: func() *appsv1.StatefulSet {
+				ss := defaulted(newDesiredStatefulSet("jupyter:1"))
+				replicas := int32(3)
+				ss.Spec.Replicas = &replicas
+				return ss
+			}(),
+			changed: true,
+			check: func(t *testing.T, ss *appsv1.StatefulSet) {
+				if *ss.Spec.Replicas != 1 {
+					t.Errorf("replicas = %v; want 1", *ss.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name:    "missing hash annotation",
+			from:    newDesiredStatefulSet("jupyter:1"),
+			to:      &appsv1.StatefulSet{},
+			changed: true,
+		},
+	}
+	for _, test := range tests {
+		changed := CopyStatefulSetFields(test.from, test.to)
+		if changed != test.changed {
+			t.Errorf("%v: CopyStatefulSetFields = %v; want %v", test.name, changed, test.changed)
+		}
+		if test.check != nil {
+			test.check(t, test.to)
+		}
+		// A second copy must be a no-op.
+		if CopyStatefulSetFields(test.from, test.to) {
+			t.Errorf("%v: second CopyStatefulSetFields changed the StatefulSet", test.name)
+		}
+	}
+}
+
+func TestCopyDaemonSetFields(t *testing.T) {
+	ss := newDesiredStatefulSet("jupyter:1")
+	from := &appsv1.DaemonSet{ObjectMeta: ss.ObjectMeta, Spec: appsv1.DaemonSetSpec{Template: ss.Spec.Template}}
+	to := from.DeepCopy()
+	to.Annotations["other-controller"] = "value"
+	to.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	if CopyDaemonSetFields(from, to) {
+		t.Errorf("CopyDaemonSetFields of a defaulted DaemonSet changed it")
+	}
+
+	ss = newDesiredStatefulSet("jupyter:2")
+	from = &appsv1.DaemonSet{ObjectMeta: ss.ObjectMeta, Spec: appsv1.DaemonSetSpec{Template: ss.Spec.Template}}
+	if !CopyDaemonSetFields(from, to) {
+		t.Errorf("CopyDaemonSetFields of a new image didn't change the DaemonSet")
+	}
+	if image := to.Spec.Template.Spec.Containers[0].Image; image != "jupyter:2" {
+		t.Errorf("image = %v; want jupyter:2", image)
+	}
+	if to.Annotations["other-controller"] != "value" {
+		t.Errorf("annotations of other controllers were removed: %v", to.Annotations)
+	}
+}
+
+func TestContainsOwnedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired interface{}
+		actual  interface{}
+		want    bool
+	}{
+		{
+			name:    "unset fields are not owned",
+			desired: corev1.Container{Name: "nb"},
+			actual:  corev1.Container{Name: "nb", ImagePullPolicy: corev1.PullAlways},
+			want:    true,
+		},
+		{
+			name:    "changed field",
+			desired: corev1.Container{Name: "nb"},
+			actual:  corev1.Container{Name: "other"},
+			want:    false,
+		},
+		{
+			name:    "added list item",
+			desired: corev1.PodSpec{Containers: []corev1.Container{{Name: "nb"}}},
+			actual:  corev1.PodSpec{Containers: []corev1.Container{{Name: "nb"}, {Name: "sidecar"}}},
+			want:    false,
+		},
+		{
+			name:    "missing map entry",
+			desired: map[string]string{"a": "1"},
+			actual:  map[string]string{"b": "1"},
+			want:    false,
+		},
+	}
+	for _, test := range tests {
+		if got := ContainsOwnedFields(test.desired, test.actual); got != test.want {
+			t.Errorf("%v: ContainsOwnedFields = %v; want %v", test.name, got, test.want)
+		}
+	}
+}
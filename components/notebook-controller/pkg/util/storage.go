@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strconv"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshotGroupVersionKind is the kind of the VolumeSnapshots of the CSI
+// snapshot API. It is an optional CRD, so VolumeSnapshots are handled as
+// unstructured objects.
+var VolumeSnapshotGroupVersionKind = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1alpha1",
+	Kind:    "VolumeSnapshot",
+}
+
+// WorkspaceClaimName returns the name of the PersistentVolumeClaim of the
+// workspace of the notebook.
+func WorkspaceClaimName(notebookName string) string {
+	return "workspace-" + notebookName
+}
+
+// DefaultStorageAnnotations mark the default StorageClass of the cluster,
+// in their beta and GA form.
+var DefaultStorageAnnotations = []string{
+	"storageclass.beta.kubernetes.io/is-default-class",
+	"storageclass.kubernetes.io/is-default-class",
+}
+
+// HasDefaultStorage returns true if one of the storage classes is the default.
+// This is the check the bootstrap server does before deploying Kubeflow.
+func HasDefaultStorage(sClasses *storagev1.StorageClassList) bool {
+	for _, i := range sClasses.Items {
+		for _, annotation := range DefaultStorageAnnotations {
+			v, has := i.GetAnnotations()[annotation]
+			if !has {
+				continue
+			}
+			if isDefault, err := strconv.ParseBool(v); err == nil && isDefault {
+				return true
+			}
+		}
+	}
+	return false
+}
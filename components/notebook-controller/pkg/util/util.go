@@ -36,6 +36,12 @@ func CopyServiceFields(from, to *corev1.Service) bool {
 			requireUpdate = true
 		}
 	}
+	// The annotations of a routing provider are added when it is selected.
+	for k := range from.Annotations {
+		if _, ok := to.Annotations[k]; !ok {
+			requireUpdate = true
+		}
+	}
 	to.Annotations = from.Annotations
 
 	// Don't copy the entire Spec, because we can't overwrite the clusterIp field
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// CRDName is the name of the Notebook CustomResourceDefinition.
+const CRDName = "notebooks.kubeflow.org"
+
+// CRDKind is the kind of the CustomResourceDefinition objects.
+var CRDKind = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1beta1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// CABundleSyncer keeps the CA bundle of the conversion webhook of the
+// Notebook CRD in sync with the CA certificate of the webhook server. The API
+// server can't convert, and so can't read or write, Notebooks of the versions
+// other than the storage version until it trusts the webhook.
+type CABundleSyncer struct {
+	Client client.Client
+	// CertDir holds the certificates of the webhook server.
+	CertDir string
+	// Period is how often the CA bundle is checked, so that rotated
+	// certificates are picked up.
+	Period time.Duration
+}
+
+var _ manager.Runnable = &CABundleSyncer{}
+
+// Start syncs the CA bundle every period until stop is closed.
+func (s *CABundleSyncer) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		if err := s.Sync(); err != nil {
+			log.Error(err, "unable to sync the CA bundle of the conversion webhook")
+		}
+	}, s.Period, stop)
+	return nil
+}
+
+// Sync sets the CA bundle of the conversion webhook once.
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+func (s *CABundleSyncer) Sync() error {
+	ctx := context.TODO()
+	// The webhook server writes its certificates when it starts.
+	ca, err := ioutil.ReadFile(filepath.Join(s.CertDir, "ca-cert.pem"))
+	if err != nil {
+		return err
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(CRDKind)
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: CRDName}, crd); err != nil {
+		return err
+	}
+	changed, err := SetCABundle(crd, ca)
+	if err != nil || !changed {
+		return err
+	}
+	log.Info("Updating the CA bundle of the conversion webhook", "name", CRDName)
+	return s.Client.Update(ctx, crd)
+}
+
+// SetCABundle sets the CA bundle of the conversion webhook of crd to ca, and
+// returns whether it changed. CRDs without webhook conversion are left alone.
+func SetCABundle(crd *unstructured.Unstructured, ca []byte) (bool, error) {
+	strategy, _, err := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	if err != nil || strategy != "Webhook" {
+		return false, err
+	}
+	caBundle := base64.StdEncoding.EncodeToString(ca)
+	path := []string{"spec", "conversion", "webhookClientConfig", "caBundle"}
+	current, _, err := unstructured.NestedString(crd.Object, path...)
+	if err != nil || current == caBundle {
+		return false, err
+	}
+	if err := unstructured.SetNestedField(crd.Object, caBundle, path...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
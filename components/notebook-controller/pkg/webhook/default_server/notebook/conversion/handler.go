@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conversion serves the CRD conversion webhook of the Notebook API.
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("conversion")
+
+// Path is the path the API server sends ConversionReviews to.
+const Path = "/convert"
+
+// The ConversionReview of apiextensions.k8s.io/v1beta1. Only the fields used
+// by the webhook are declared.
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// Handler converts Notebooks between v1alpha1 and v1beta1.
+type Handler struct{}
+
+var _ http.Handler = &Handler{}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := conversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "the ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	resp := &conversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+	for _, obj := range review.Request.Objects {
+		converted, err := Convert(obj.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			log.Error(err, "conversion failed", "uid", review.Request.UID)
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+			}
+			break
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	review.Request = nil
+	review.Response = resp
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&review); err != nil {
+		log.Error(err, "unable to write the ConversionReview response")
+	}
+}
+
+// Convert converts the JSON of a Notebook to the desired API version.
+func Convert(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	var out interface{}
+	switch {
+	case typeMeta.APIVersion == v1alpha1.SchemeGroupVersion.String() && desiredAPIVersion == v1beta1.SchemeGroupVersion.String():
+		in := &v1alpha1.Notebook{}
+		if err := json.Unmarshal(raw, in); err != nil {
+			return nil, err
+		}
+		nb := &v1beta1.Notebook{}
+		if err := v1beta1.Convert_v1alpha1_Notebook_To_v1beta1_Notebook(in, nb); err != nil {
+			return nil, err
+		}
+		out = nb
+	case typeMeta.APIVersion == v1beta1.SchemeGroupVersion.String() && desiredAPIVersion == v1alpha1.SchemeGroupVersion.String():
+		in := &v1beta1.Notebook{}
+		if err := json.Unmarshal(raw, in); err != nil {
+			return nil, err
+		}
+		nb := &v1alpha1.Notebook{}
+		if err := v1beta1.Convert_v1beta1_Notebook_To_v1alpha1_Notebook(in, nb); err != nil {
+			return nil, err
+		}
+		out = nb
+	default:
+		return nil, fmt.Errorf("unsupported conversion of %v %v to %v", typeMeta.APIVersion, typeMeta.Kind, desiredAPIVersion)
+	}
+	return json.Marshal(out)
+}
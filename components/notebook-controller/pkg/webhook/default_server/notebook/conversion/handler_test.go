@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	alphaNotebook = `{"apiVersion":"kubeflow.org/v1alpha1","kind":"Notebook",` +
+		`"metadata":{"name":"nb","namespace":"test"},` +
+		`"spec":{"template":{"spec":{"containers":[{"name":"nb","image":"jupyter:1"}]}}}}`
+	betaNotebook = `{"apiVersion":"kubeflow.org/v1beta1","kind":"Notebook",` +
+		`"metadata":{"name":"nb","namespace":"test"},` +
+		`"spec":{"image":"jupyter:2","template":{"spec":{"containers":[{"name":"nb"}]}}}}`
+)
+
+var (
+	alphaVersion = v1alpha1.SchemeGroupVersion.String()
+	betaVersion  = v1beta1.SchemeGroupVersion.String()
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		desired   string
+		wantImage string
+		wantErr   bool
+	}{
+		{name: "v1alpha1 to v1beta1", in: alphaNotebook, desired: betaVersion, wantImage: "jupyter:1"},
+		{name: "v1beta1 to v1alpha1", in: betaNotebook, desired: alphaVersion, wantImage: "jupyter:2"},
+		{name: "same version", in: alphaNotebook, desired: alphaVersion, wantImage: "jupyter:1"},
+		{name: "unsupported version", in: alphaNotebook, desired: "kubeflow.org/v2", wantErr: true},
+		{name: "invalid JSON", in: `{"apiVersion":`, desired: betaVersion, wantErr: true},
+	}
+
+	for _, test := range tests {
+		out, err := Convert([]byte(test.in), test.desired)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if got := notebookImage(t, out); got != test.wantImage {
+			t.Errorf("%s: image = %q; want %q", test.name, got, test.wantImage)
+		}
+	}
+}
+
+// notebookImage returns the image of the notebook in raw, read according to
+// its API version.
+func notebookImage(t *testing.T, raw []byte) string {
+	t.Helper()
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		t.Fatal(err)
+	}
+	switch typeMeta.APIVersion {
+	case alphaVersion:
+		nb := &v1alpha1.Notebook{}
+		if err := json.Unmarshal(raw, nb); err != nil {
+			t.Fatal(err)
+		}
+		return nb.Spec.Template.Spec.Containers[0].Image
+	case betaVersion:
+		nb := &v1beta1.Notebook{}
+		if err := json.Unmarshal(raw, nb); err != nil {
+			t.Fatal(err)
+		}
+		return nb.Spec.Image
+	}
+	t.Fatalf("unexpected API version %q", typeMeta.APIVersion)
+	return ""
+}
+
+// serve posts body to the handler and returns the response.
+func serve(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", Path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	(&Handler{}).ServeHTTP(w, req)
+	return w
+}
+
+func newReview(t *testing.T, desired string, objects ...string) string {
+	t.Helper()
+	review := conversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "ConversionReview"},
+		Request:  &conversionRequest{UID: "uid-1", DesiredAPIVersion: desired},
+	}
+	for _, obj := range objects {
+		review.Request.Objects = append(review.Request.Objects, runtime.RawExtension{Raw: []byte(obj)})
+	}
+	data, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantStatus string
+		wantImages []string
+	}{
+		{
+			name:       "converts all objects",
+			body:       newReview(t, betaVersion, alphaNotebook, betaNotebook),
+			wantCode:   http.StatusOK,
+			wantStatus: metav1.StatusSuccess,
+			wantImages: []string{"jupyter:1", "jupyter:2"},
+		},
+		{
+			name:       "fails the whole review",
+			body:       newReview(t, "kubeflow.org/v2", alphaNotebook),
+			wantCode:   http.StatusOK,
+			wantStatus: metav1.StatusFailure,
+		},
+		{
+			name:     "invalid body",
+			body:     "not json",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "no request",
+			body:     `{"apiVersion":"apiextensions.k8s.io/v1beta1","kind":"ConversionReview"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		w := serve(t, test.body)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: status code = %v; want %v", test.name, w.Code, test.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		review := conversionReview{}
+		if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if review.Request != nil || review.Response == nil {
+			t.Errorf("%s: got request %v and response %v; want only a response", test.name, review.Request, review.Response)
+			continue
+		}
+		resp := review.Response
+		if resp.UID != "uid-1" || resp.Result.Status != test.wantStatus {
+			t.Errorf("%s: got uid %q, status %q; want uid-1, %q", test.name, resp.UID, resp.Result.Status, test.wantStatus)
+		}
+		if len(resp.ConvertedObjects) != len(test.wantImages) {
+			t.Errorf("%s: got %d converted objects; want %d", test.name, len(resp.ConvertedObjects), len(test.wantImages))
+			continue
+		}
+		for i, obj := range resp.ConvertedObjects {
+			if got := notebookImage(t, obj.Raw); got != test.wantImages[i] {
+				t.Errorf("%s: image of object %d = %q; want %q", test.name, i, got, test.wantImages[i])
+			}
+			if !bytes.Contains(obj.Raw, []byte(`"apiVersion":"`+betaVersion+`"`)) {
+				t.Errorf("%s: object %d not converted to %v: %s", test.name, i, betaVersion, obj.Raw)
+			}
+		}
+	}
+}
+
+func TestSetCABundle(t *testing.T) {
+	newCRD := func(strategy string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"conversion": map[string]interface{}{"strategy": strategy},
+			},
+		}}
+	}
+	ca := []byte("ca")
+	caBundle := base64.StdEncoding.EncodeToString(ca)
+
+	crd := newCRD("Webhook")
+	if changed, err := SetCABundle(crd, ca); err != nil || !changed {
+		t.Fatalf("SetCABundle = %v, %v; want changed", changed, err)
+	}
+	got, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "caBundle")
+	if got != caBundle {
+		t.Errorf("caBundle = %q; want %q", got, caBundle)
+	}
+	if changed, err := SetCABundle(crd, ca); err != nil || changed {
+		t.Errorf("SetCABundle of the same CA = %v, %v; want unchanged", changed, err)
+	}
+	if changed, err := SetCABundle(crd, []byte("rotated")); err != nil || !changed {
+		t.Errorf("SetCABundle of a rotated CA = %v, %v; want changed", changed, err)
+	}
+
+	crd = newCRD("None")
+	if changed, err := SetCABundle(crd, ca); err != nil || changed {
+		t.Errorf("SetCABundle without webhook conversion = %v, %v; want unchanged", changed, err)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutating
+
+import (
+	"context"
+	"net/http"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+func init() {
+	webhookName := "mutating-create-update-notebook"
+	if HandlerMap[webhookName] == nil {
+		HandlerMap[webhookName] = []admission.Handler{}
+	}
+	HandlerMap[webhookName] = append(HandlerMap[webhookName], &NotebookCreateUpdateHandler{})
+}
+
+// NotebookCreateUpdateHandler sets the defaults of a Notebook.
+type NotebookCreateUpdateHandler struct {
+	// Client reads the snapshots notebooks are cloned from.
+	Client client.Client
+
+	// Decoder decodes objects
+	Decoder types.Decoder
+}
+
+func (h *NotebookCreateUpdateHandler) mutatingNotebookFn(ctx context.Context, obj *v1alpha1.Notebook) error {
+	if err := h.setCloneDefaults(ctx, obj); err != nil {
+		return err
+	}
+	v1alpha1.SetNotebookDefaults(obj)
+	return nil
+}
+
+// setCloneDefaults gives a notebook cloned from a snapshot the workspace of the
+// snapshot, so the workspace is restored unless the notebook sets its own. It
+// does nothing if the snapshot doesn't exist or hasn't captured the notebook yet.
+func (h *NotebookCreateUpdateHandler) setCloneDefaults(ctx context.Context, obj *v1alpha1.Notebook) error {
+	if obj.Spec.CloneFrom == "" || obj.Spec.Workspace != nil {
+		return nil
+	}
+	snapshot := &v1alpha1.NotebookSnapshot{}
+	err := h.Client.Get(ctx, client.ObjectKey{Name: obj.Spec.CloneFrom, Namespace: obj.Namespace}, snapshot)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if spec := snapshot.Status.NotebookSpec; spec != nil && spec.Workspace != nil {
+		obj.Spec.Workspace = spec.Workspace.DeepCopy()
+	}
+	return nil
+}
+
+var _ admission.Handler = &NotebookCreateUpdateHandler{}
+
+// Handle handles admission requests.
+func (h *NotebookCreateUpdateHandler) Handle(ctx context.Context, req types.Request) types.Response {
+	obj := &v1alpha1.Notebook{}
+
+	err := h.Decoder.Decode(req, obj)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	copy := obj.DeepCopy()
+
+	err = h.mutatingNotebookFn(ctx, copy)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponse(obj, copy)
+}
+
+var _ inject.Client = &NotebookCreateUpdateHandler{}
+
+// InjectClient injects the client into the NotebookCreateUpdateHandler
+func (h *NotebookCreateUpdateHandler) InjectClient(c client.Client) error {
+	h.Client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder into the NotebookCreateUpdateHandler
+func (h *NotebookCreateUpdateHandler) InjectDecoder(d types.Decoder) error {
+	h.Decoder = d
+	return nil
+}
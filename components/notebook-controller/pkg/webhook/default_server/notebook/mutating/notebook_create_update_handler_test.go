@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutating
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func init() {
+	// The fake client decodes objects with the client-go scheme.
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+func newTestNotebook() *v1alpha1.Notebook {
+	nb := &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{Name: "nb", Namespace: "test"},
+	}
+	nb.Spec.Template.Spec.Containers = []corev1.Container{{Name: "nb", Image: "jupyter:1"}}
+	return nb
+}
+
+// defaultedPodSpec returns the PodSpec of newTestNotebook with its defaults.
+func defaultedPodSpec() corev1.PodSpec {
+	fsGroup := v1alpha1.DefaultFSGroup
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:       "nb",
+			Image:      "jupyter:1",
+			WorkingDir: v1alpha1.DefaultWorkingDir,
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: v1alpha1.DefaultContainerPort,
+				Name:          v1alpha1.DefaultContainerPortName,
+				Protocol:      corev1.ProtocolTCP,
+			}},
+		}},
+		SecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+	}
+}
+
+func TestMutatingNotebookFn(t *testing.T) {
+	snapshotWorkspace := &v1alpha1.WorkspaceSpec{
+		Size:         resource.MustParse("5Gi"),
+		MountPath:    "/data",
+		RetainPolicy: v1alpha1.WorkspaceDelete,
+	}
+	snapshot := &v1alpha1.NotebookSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snap", Namespace: "test"},
+		Status: v1alpha1.NotebookSnapshotStatus{
+			NotebookSpec: &v1alpha1.NotebookSpec{Workspace: snapshotWorkspace},
+		},
+	}
+	ownWorkspace := &v1alpha1.WorkspaceSpec{Size: resource.MustParse("1Gi")}
+
+	tests := []struct {
+		name          string
+		objects       []runtime.Object
+		modify        func(nb *v1alpha1.Notebook)
+		wantPodSpec   corev1.PodSpec
+		wantWorkspace *v1alpha1.WorkspaceSpec
+	}{
+		{
+			name:        "inline PodSpec",
+			modify:      func(nb *v1alpha1.Notebook) {},
+			wantPodSpec: defaultedPodSpec(),
+		},
+		{
+			name: "set fields are kept",
+			modify: func(nb *v1alpha1.Notebook) {
+				c := &nb.Spec.Template.Spec.Containers[0]
+				c.WorkingDir = "/work"
+				c.Ports = []corev1.ContainerPort{}
+			},
+			wantPodSpec: func() corev1.PodSpec {
+				spec := defaultedPodSpec()
+				spec.Containers[0].WorkingDir = "/work"
+				spec.Containers[0].Ports = []corev1.ContainerPort{}
+				return spec
+			}(),
+		},
+		{
+			name: "workspace",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Workspace = ownWorkspace.DeepCopy()
+			},
+			wantPodSpec: defaultedPodSpec(),
+			wantWorkspace: &v1alpha1.WorkspaceSpec{
+				Size:         resource.MustParse("1Gi"),
+				AccessMode:   corev1.ReadWriteOnce,
+				MountPath:    v1alpha1.DefaultWorkingDir,
+				RetainPolicy: v1alpha1.WorkspaceRetain,
+			},
+		},
+		{
+			// The PodSpec is merged into the one of the template by the controller.
+			name: "template",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.TemplateRef = &v1alpha1.TemplateReference{Name: "tf"}
+			},
+			wantPodSpec: newTestNotebook().Spec.Template.Spec,
+		},
+		{
+			name:    "clone gets the workspace of the snapshot",
+			objects: []runtime.Object{snapshot},
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.CloneFrom = "snap"
+			},
+			wantPodSpec: newTestNotebook().Spec.Template.Spec,
+			wantWorkspace: &v1alpha1.WorkspaceSpec{
+				Size:         resource.MustParse("5Gi"),
+				AccessMode:   corev1.ReadWriteOnce,
+				MountPath:    "/data",
+				RetainPolicy: v1alpha1.WorkspaceDelete,
+			},
+		},
+		{
+			name:    "clone keeps its own workspace",
+			objects: []runtime.Object{snapshot},
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.CloneFrom = "snap"
+				nb.Spec.Workspace = ownWorkspace.DeepCopy()
+			},
+			wantPodSpec: newTestNotebook().Spec.Template.Spec,
+			wantWorkspace: &v1alpha1.WorkspaceSpec{
+				Size:         resource.MustParse("1Gi"),
+				AccessMode:   corev1.ReadWriteOnce,
+				MountPath:    v1alpha1.DefaultWorkingDir,
+				RetainPolicy: v1alpha1.WorkspaceRetain,
+			},
+		},
+		{
+			name: "clone of a missing snapshot",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.CloneFrom = "snap"
+			},
+			wantPodSpec: newTestNotebook().Spec.Template.Spec,
+		},
+	}
+
+	for _, test := range tests {
+		h := &NotebookCreateUpdateHandler{Client: fake.NewFakeClient(test.objects...)}
+		nb := newTestNotebook()
+		test.modify(nb)
+		if err := h.mutatingNotebookFn(context.TODO(), nb); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(nb.Spec.Template.Spec, test.wantPodSpec) {
+			t.Errorf("%s: got PodSpec %+v; want %+v", test.name, nb.Spec.Template.Spec, test.wantPodSpec)
+		}
+		if !reflect.DeepEqual(nb.Spec.Workspace, test.wantWorkspace) {
+			t.Errorf("%s: got workspace %+v; want %+v", test.name, nb.Spec.Workspace, test.wantWorkspace)
+		}
+	}
+	if snapshotWorkspace.AccessMode != "" {
+		t.Errorf("the workspace of the snapshot was modified")
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/podspec"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/policy"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/schedule"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+func init() {
+	webhookName := "validating-create-update-notebook"
+	if HandlerMap[webhookName] == nil {
+		HandlerMap[webhookName] = []admission.Handler{}
+	}
+	HandlerMap[webhookName] = append(HandlerMap[webhookName], &NotebookCreateUpdateHandler{})
+}
+
+// NotebookCreateUpdateHandler rejects invalid Notebooks, and the ones that
+// violate a NotebookPolicy of their namespace.
+type NotebookCreateUpdateHandler struct {
+	// Client reads the policies and templates.
+	Client client.Client
+
+	// Decoder decodes objects
+	Decoder types.Decoder
+}
+
+// validatingNotebookFn checks that the notebook can be turned into a StatefulSet
+// and a Service, and complies with the policies of its namespace. old is the
+// notebook before an update, nil on create. Updates that leave the spec alone,
+// like those of annotations and finalizers, are allowed unless they set an
+// unknown routing provider, and updates of deleted notebooks always are, so
+// that a notebook violating a policy added later can still be stopped, culled
+// and deleted. The policies are only checked when a notebook is created or its
+// PodSpec changes, and the number of notebooks only on create. The returned
+// reason lists all problems found.
+func (h *NotebookCreateUpdateHandler) validatingNotebookFn(ctx context.Context, obj, old *v1alpha1.Notebook) (bool, string, error) {
+	if old != nil && obj.DeletionTimestamp != nil {
+		return true, "allowed to be admitted", nil
+	}
+	errs := validateRoutingProvider(obj, old)
+	if old == nil || !reflect.DeepEqual(obj.Spec, old.Spec) {
+		errs = append(errs, validateNotebook(obj)...)
+		policyErrs, err := h.checkPolicies(ctx, obj, old)
+		if err != nil {
+			return false, "", err
+		}
+		errs = append(errs, policyErrs...)
+	}
+	if len(errs) > 0 {
+		return false, errs.ToAggregate().Error(), nil
+	}
+	return true, "allowed to be admitted", nil
+}
+
+// validateNotebook returns the problems of the notebook spec.
+func validateNotebook(obj *v1alpha1.Notebook) field.ErrorList {
+	errs := field.ErrorList{}
+
+	// The name is used for the Service, and with a "-0" suffix for the Pod.
+	for _, msg := range validation.IsDNS1123Label(obj.Name) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), obj.Name, msg))
+	}
+
+	specPath := field.NewPath("spec", "template", "spec")
+	containers := obj.Spec.Template.Spec.Containers
+	ref := obj.Spec.TemplateRef
+	if ref != nil {
+		// The containers may all come from the template.
+		errs = append(errs, validateTemplateRef(ref, field.NewPath("spec", "templateRef"))...)
+	}
+	if obj.Spec.CloneFrom != "" {
+		// Or from the snapshot.
+		if ref != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "cloneFrom"), "a notebook may not have both a templateRef and cloneFrom"))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(obj.Spec.CloneFrom) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "cloneFrom"), obj.Spec.CloneFrom, msg))
+		}
+	} else if ref == nil && len(containers) == 0 {
+		errs = append(errs, field.Required(specPath.Child("containers"), "the notebook container must be specified"))
+	}
+
+	portNames := map[string]bool{}
+	portNumbers := map[string]bool{}
+	for i, c := range containers {
+		for j, p := range c.Ports {
+			portPath := specPath.Child("containers").Index(i).Child("ports").Index(j)
+			if p.Name != "" {
+				if portNames[p.Name] {
+					errs = append(errs, field.Duplicate(portPath.Child("name"), p.Name))
+				}
+				portNames[p.Name] = true
+			}
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			key := fmt.Sprintf("%v/%v", p.ContainerPort, protocol)
+			if portNumbers[key] {
+				errs = append(errs, field.Duplicate(portPath.Child("containerPort"), p.ContainerPort))
+			}
+			portNumbers[key] = true
+		}
+	}
+	// Whether the exposed ports exist may depend on the template, so it is
+	// checked by the controller.
+	exposed := map[string]bool{}
+	for i, name := range obj.Spec.ExposedPorts {
+		fldPath := field.NewPath("spec", "exposedPorts").Index(i)
+		for _, msg := range validation.IsValidPortName(name) {
+			errs = append(errs, field.Invalid(fldPath, name, msg))
+		}
+		if name == routing.NotebookPortName {
+			errs = append(errs, field.Forbidden(fldPath, "the notebook server is always exposed"))
+		}
+		if exposed[name] {
+			errs = append(errs, field.Duplicate(fldPath, name))
+		}
+		exposed[name] = true
+	}
+	if obj.Spec.ServiceAccount != nil && obj.Spec.Template.Spec.ServiceAccountName != "" {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "serviceAccount"),
+			"a notebook may not have both a serviceAccount and a serviceAccountName in its Pod spec"))
+	}
+	if sc := obj.Spec.Schedule; sc != nil {
+		if _, err := schedule.NewWindow(sc.Start, sc.Stop, sc.TimeZone); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "schedule"), *sc, err.Error()))
+		}
+	}
+	if ws := obj.Spec.Workspace; ws != nil {
+		errs = append(errs, validateWorkspace(ws, field.NewPath("spec", "workspace"))...)
+	}
+	return errs
+}
+
+// validateRoutingProvider returns an error if the routing provider annotation
+// of the notebook names an unknown provider, which the controller would fail to
+// reconcile. It is only checked when it is set or changed.
+func validateRoutingProvider(obj, old *v1alpha1.Notebook) field.ErrorList {
+	errs := field.ErrorList{}
+	name, ok := obj.Annotations[routing.ProviderAnnotation]
+	if !ok || old != nil && old.Annotations[routing.ProviderAnnotation] == name {
+		return errs
+	}
+	for _, provider := range routing.ProviderNames {
+		if name == provider {
+			return errs
+		}
+	}
+	fldPath := field.NewPath("metadata", "annotations").Key(routing.ProviderAnnotation)
+	return append(errs, field.NotSupported(fldPath, name, routing.ProviderNames))
+}
+
+// checkPolicies returns the violations of the policies of the namespace by the
+// notebook. old is the notebook before an update, nil on create.
+func (h *NotebookCreateUpdateHandler) checkPolicies(ctx context.Context, obj, old *v1alpha1.Notebook) (field.ErrorList, error) {
+	errs := field.ErrorList{}
+	policies, err := policy.List(ctx, h.Client, obj.Namespace)
+	if err != nil || len(policies) == 0 {
+		return errs, err
+	}
+
+	create := old == nil
+	count := 0
+	if create && policy.HasMaxNotebooks(policies) {
+		notebooks := &v1alpha1.NotebookList{}
+		if err := h.Client.List(ctx, client.InNamespace(obj.Namespace), notebooks); err != nil {
+			return nil, err
+		}
+		count = len(notebooks.Items)
+	}
+	// A notebook may be created before its template, or before its snapshot is
+	// ready, so its PodSpec is then only checked by the controller.
+	podSpec, err := podspec.Resolve(ctx, h.Client, obj)
+	if err != nil && !podspec.IsPending(err) {
+		return nil, err
+	}
+	if podSpec != nil && !create {
+		// The violations of an unchanged PodSpec are reported by the controller.
+		oldPodSpec, err := podspec.Resolve(ctx, h.Client, old)
+		if err == nil && reflect.DeepEqual(podSpec, oldPodSpec) {
+			podSpec = nil
+		}
+	}
+	for i := range policies {
+		if podSpec != nil {
+			errs = append(errs, policy.Check(&policies[i], podSpec)...)
+		}
+		if create {
+			if err := policy.CheckCount(&policies[i], count); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs, nil
+}
+
+// validateTemplateRef returns the problems of the reference to the template of the notebook.
+func validateTemplateRef(ref *v1alpha1.TemplateReference, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch ref.Kind {
+	case "", v1alpha1.NotebookTemplateKind, v1alpha1.ClusterNotebookTemplateKind:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("kind"), ref.Kind,
+			[]string{v1alpha1.NotebookTemplateKind, v1alpha1.ClusterNotebookTemplateKind}))
+	}
+	if ref.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), "the name of the template must be specified"))
+	}
+	return errs
+}
+
+// validateWorkspace returns the problems of the workspace volume.
+func validateWorkspace(ws *v1alpha1.WorkspaceSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if ws.Size.Sign() <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("size"), ws.Size.String(), "must be greater than zero"))
+	}
+	switch ws.AccessMode {
+	case "", corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("accessMode"), ws.AccessMode,
+			[]string{string(corev1.ReadWriteOnce), string(corev1.ReadOnlyMany), string(corev1.ReadWriteMany)}))
+	}
+	if ws.MountPath != "" && !path.IsAbs(ws.MountPath) {
+		errs = append(errs, field.Invalid(fldPath.Child("mountPath"), ws.MountPath, "must be an absolute path"))
+	}
+	switch ws.RetainPolicy {
+	case "", v1alpha1.WorkspaceRetain, v1alpha1.WorkspaceDelete:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("retainPolicy"), ws.RetainPolicy,
+			[]string{string(v1alpha1.WorkspaceRetain), string(v1alpha1.WorkspaceDelete)}))
+	}
+	return errs
+}
+
+var _ admission.Handler = &NotebookCreateUpdateHandler{}
+
+// Handle handles admission requests.
+func (h *NotebookCreateUpdateHandler) Handle(ctx context.Context, req types.Request) types.Response {
+	obj := &v1alpha1.Notebook{}
+
+	err := h.Decoder.Decode(req, obj)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	var old *v1alpha1.Notebook
+	if req.AdmissionRequest.Operation == admissionv1beta1.Update {
+		// The decoder reads the object of the request, so it is given one
+		// whose object is the old one.
+		oldReq := *req.AdmissionRequest
+		oldReq.Object = oldReq.OldObject
+		old = &v1alpha1.Notebook{}
+		if err := h.Decoder.Decode(types.Request{AdmissionRequest: &oldReq}, old); err != nil {
+			return admission.ErrorResponse(http.StatusBadRequest, err)
+		}
+	}
+	allowed, reason, err := h.validatingNotebookFn(ctx, obj, old)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	return admission.ValidationResponse(allowed, reason)
+}
+
+var _ inject.Client = &NotebookCreateUpdateHandler{}
+
+// InjectClient injects the client into the NotebookCreateUpdateHandler
+func (h *NotebookCreateUpdateHandler) InjectClient(c client.Client) error {
+	h.Client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder into the NotebookCreateUpdateHandler
+func (h *NotebookCreateUpdateHandler) InjectDecoder(d types.Decoder) error {
+	h.Decoder = d
+	return nil
+}
@@ -0,0 +1,401 @@
+/*
+Copyright 2019 The Kubeflow Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis"
+	v1alpha1 "github.com/kubeflow/kubeflow/components/notebook-controller/pkg/apis/notebook/v1alpha1"
+	"github.com/kubeflow/kubeflow/components/notebook-controller/pkg/routing"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+func init() {
+	// The fake client decodes objects with the client-go scheme.
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+func newTestNotebook(name string) *v1alpha1.Notebook {
+	nb := &v1alpha1.Notebook{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+	}
+	nb.Spec.Template.Spec.Containers = []corev1.Container{{Name: "nb", Image: "jupyter:1"}}
+	return nb
+}
+
+func TestValidateNotebook(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(nb *v1alpha1.Notebook)
+		// want are the messages of the expected errors, none if empty.
+		want []string
+	}{
+		{
+			name:   "valid",
+			modify: func(nb *v1alpha1.Notebook) {},
+		},
+		{
+			name: "no containers",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers = nil
+			},
+			want: []string{"spec.template.spec.containers: Required value: the notebook container must be specified"},
+		},
+		{
+			name: "containers from the template",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers = nil
+				nb.Spec.TemplateRef = &v1alpha1.TemplateReference{Name: "tf"}
+			},
+		},
+		{
+			name: "template and snapshot",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.TemplateRef = &v1alpha1.TemplateReference{Name: "tf"}
+				nb.Spec.CloneFrom = "snap"
+			},
+			want: []string{"spec.cloneFrom: Forbidden: a notebook may not have both a templateRef and cloneFrom"},
+		},
+		{
+			name: "duplicate ports",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
+					{Name: "http", ContainerPort: 8888},
+					{Name: "http", ContainerPort: 8888, Protocol: corev1.ProtocolTCP},
+					{Name: "dns", ContainerPort: 8888, Protocol: corev1.ProtocolUDP},
+				}
+			},
+			want: []string{
+				`spec.template.spec.containers[0].ports[1].name: Duplicate value: "http"`,
+				`spec.template.spec.containers[0].ports[1].containerPort: Duplicate value: 8888`,
+			},
+		},
+		{
+			name: "duplicate ports of different containers",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.Template.Spec.Containers = []corev1.Container{
+					{Name: "nb", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8888}}},
+					{Name: "sidecar", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+				}
+			},
+			want: []string{`spec.template.spec.containers[1].ports[0].name: Duplicate value: "http"`},
+		},
+		{
+			name: "name is not a DNS label",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Name = "My_Notebook"
+			},
+			want: []string{`metadata.name: Invalid value: "My_Notebook": a DNS-1123 label must consist of lower case alphanumeric characters or '-'`},
+		},
+		{
+			name: "snapshot name is not a DNS subdomain",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.CloneFrom = "Snap_1"
+			},
+			want: []string{`spec.cloneFrom: Invalid value: "Snap_1": a DNS-1123 subdomain must consist of lower case alphanumeric characters`},
+		},
+		{
+			name: "notebook port exposed",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Spec.ExposedPorts = []string{"notebook"}
+			},
+			want: []string{"spec.exposedPorts[0]: Forbidden: the notebook server is always exposed"},
+		},
+	}
+
+	for _, test := range tests {
+		nb := newTestNotebook("nb")
+		test.modify(nb)
+		errs := validateNotebook(nb)
+		if len(errs) != len(test.want) {
+			t.Errorf("%s: got errors %v; want %d errors", test.name, errs, len(test.want))
+			continue
+		}
+		for i, err := range errs {
+			if !strings.HasPrefix(err.Error(), test.want[i]) {
+				t.Errorf("%s: got error %q; want %q", test.name, err.Error(), test.want[i])
+			}
+		}
+	}
+}
+
+func TestValidateRoutingProvider(t *testing.T) {
+	withProvider := func(name string) *v1alpha1.Notebook {
+		nb := newTestNotebook("nb")
+		nb.Annotations = map[string]string{routing.ProviderAnnotation: name}
+		return nb
+	}
+	unknown := `metadata.annotations[notebooks.kubeflow.org/routing-provider]: Unsupported value: "nginx": ` +
+		`supported values: "ambassador", "istio", "ingress"`
+	tests := []struct {
+		name string
+		nb   *v1alpha1.Notebook
+		old  *v1alpha1.Notebook
+		want []string
+	}{
+		{name: "default provider", nb: newTestNotebook("nb")},
+		{name: "known provider", nb: withProvider("istio")},
+		{name: "unknown provider", nb: withProvider("nginx"), want: []string{unknown}},
+		{name: "set to an unknown provider", nb: withProvider("nginx"), old: newTestNotebook("nb"), want: []string{unknown}},
+		// The notebook may have been created before the webhook checked the annotation.
+		{name: "unchanged unknown provider", nb: withProvider("nginx"), old: withProvider("nginx")},
+	}
+	for _, test := range tests {
+		errs := validateRoutingProvider(test.nb, test.old)
+		if len(errs) != len(test.want) {
+			t.Errorf("%s: got errors %v; want %v", test.name, errs, test.want)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != test.want[i] {
+				t.Errorf("%s: got error %q; want %q", test.name, err.Error(), test.want[i])
+			}
+		}
+	}
+}
+
+func TestCheckPolicies(t *testing.T) {
+	max := int32(1)
+	policy := &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "test"},
+		Spec: v1alpha1.NotebookPolicySpec{
+			MaxNotebooks:  &max,
+			AllowedImages: []string{"jupyter:*"},
+		},
+	}
+	existing := newTestNotebook("nb1")
+	other := newTestNotebook("nb2")
+	other.Namespace = "other"
+	tooMany := "metadata.namespace: Forbidden: the namespace may have at most 1 notebooks by NotebookPolicy default"
+	badImage := `spec.template.spec.containers[0].image: Forbidden: image "evil:1" is not allowed by NotebookPolicy default`
+
+	evil := func(name string) *v1alpha1.Notebook {
+		nb := newTestNotebook(name)
+		nb.Spec.Template.Spec.Containers[0].Image = "evil:1"
+		return nb
+	}
+	stoppedEvil := evil("nb1")
+	stoppedEvil.Spec.Stopped = true
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		nb      *v1alpha1.Notebook
+		// old is the notebook before an update, nil on create.
+		old  *v1alpha1.Notebook
+		want []string
+	}{
+		{
+			name:    "first notebook",
+			objects: []runtime.Object{policy, other},
+			nb:      newTestNotebook("nb1"),
+		},
+		{
+			name:    "create over the limit",
+			objects: []runtime.Object{policy, existing},
+			nb:      newTestNotebook("nb3"),
+			want:    []string{tooMany},
+		},
+		{
+			name:    "update over the limit",
+			objects: []runtime.Object{policy, existing, newTestNotebook("nb3")},
+			nb:      newTestNotebook("nb3"),
+			old:     newTestNotebook("nb3"),
+		},
+		{
+			name:    "image on create",
+			objects: []runtime.Object{policy},
+			nb:      evil("nb1"),
+			want:    []string{badImage},
+		},
+		{
+			name:    "image on update",
+			objects: []runtime.Object{policy, existing},
+			nb:      evil("nb1"),
+			old:     existing,
+			want:    []string{badImage},
+		},
+		{
+			// The notebook was created before the policy.
+			name:    "update of a violating notebook without changing its PodSpec",
+			objects: []runtime.Object{policy, evil("nb1")},
+			nb:      stoppedEvil,
+			old:     evil("nb1"),
+		},
+		{
+			name:    "no policies",
+			objects: []runtime.Object{existing},
+			nb:      newTestNotebook("nb3"),
+		},
+	}
+
+	for _, test := range tests {
+		h := &NotebookCreateUpdateHandler{Client: fake.NewFakeClient(test.objects...)}
+		errs, err := h.checkPolicies(context.TODO(), test.nb, test.old)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(errs) != len(test.want) {
+			t.Errorf("%s: got errors %v; want %v", test.name, errs, test.want)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != test.want[i] {
+				t.Errorf("%s: got error %q; want %q", test.name, err.Error(), test.want[i])
+			}
+		}
+	}
+}
+
+func TestValidatingNotebookFn(t *testing.T) {
+	max := int32(0)
+	policy := &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "test"},
+		Spec: v1alpha1.NotebookPolicySpec{
+			MaxNotebooks:  &max,
+			AllowedImages: []string{"jupyter:*"},
+		},
+	}
+	h := &NotebookCreateUpdateHandler{Client: fake.NewFakeClient(policy)}
+
+	nb := newTestNotebook("nb")
+	nb.Spec.Template.Spec.Containers = nil
+	allowed, reason, err := h.validatingNotebookFn(context.TODO(), nb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reason lists the problems of the spec and the policy violations.
+	want := "[spec.template.spec.containers: Required value: the notebook container must be specified, " +
+		"metadata.namespace: Forbidden: the namespace may have at most 0 notebooks by NotebookPolicy default]"
+	if allowed || reason != want {
+		t.Errorf("got %v, %q; want rejected with %q", allowed, reason, want)
+	}
+
+	allowed, _, err = h.validatingNotebookFn(context.TODO(), newTestNotebook("nb"), newTestNotebook("nb"))
+	if err != nil || !allowed {
+		t.Errorf("got %v, %v on update; want allowed", allowed, err)
+	}
+
+	// A notebook that violates a policy added after it was created can still
+	// be stopped, annotated, released and deleted, but not changed further.
+	violating := newTestNotebook("nb")
+	violating.Spec.Template.Spec.Containers[0].Image = "evil:1"
+	violating.Finalizers = []string{"notebooks.kubeflow.org/cleanup"}
+	now := metav1.Now()
+	updates := []struct {
+		name        string
+		modify      func(nb *v1alpha1.Notebook)
+		wantAllowed bool
+	}{
+		{name: "stop", modify: func(nb *v1alpha1.Notebook) { nb.Spec.Stopped = true }, wantAllowed: true},
+		{
+			name: "annotate",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Annotations = map[string]string{"notebooks.kubeflow.org/last-activity": "now"}
+			},
+			wantAllowed: true,
+		},
+		{name: "release", modify: func(nb *v1alpha1.Notebook) { nb.Finalizers = nil }, wantAllowed: true},
+		{
+			name: "deleted",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.DeletionTimestamp = &now
+				nb.Spec.Template.Spec.Containers[0].Image = "evil:2"
+			},
+			wantAllowed: true,
+		},
+		{name: "change the image", modify: func(nb *v1alpha1.Notebook) { nb.Spec.Template.Spec.Containers[0].Image = "evil:2" }},
+		{
+			name: "set an unknown routing provider",
+			modify: func(nb *v1alpha1.Notebook) {
+				nb.Annotations = map[string]string{routing.ProviderAnnotation: "nginx"}
+			},
+		},
+	}
+	for _, update := range updates {
+		nb := violating.DeepCopy()
+		update.modify(nb)
+		allowed, reason, err := h.validatingNotebookFn(context.TODO(), nb, violating)
+		if err != nil || allowed != update.wantAllowed {
+			t.Errorf("%s: got %v, %q, %v; want allowed %v", update.name, allowed, reason, err, update.wantAllowed)
+		}
+	}
+}
+
+func TestHandleUpdate(t *testing.T) {
+	policy := &v1alpha1.NotebookPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "test"},
+		Spec:       v1alpha1.NotebookPolicySpec{AllowedImages: []string{"jupyter:*"}},
+	}
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &NotebookCreateUpdateHandler{Client: fake.NewFakeClient(policy), Decoder: decoder}
+	raw := func(image string, stopped bool) runtime.RawExtension {
+		nb := newTestNotebook("nb")
+		nb.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "Notebook"}
+		nb.Spec.Template.Spec.Containers[0].Image = image
+		nb.Spec.Stopped = stopped
+		data, err := json.Marshal(nb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	tests := []struct {
+		name        string
+		object      runtime.RawExtension
+		wantAllowed bool
+		wantReason  string
+	}{
+		{name: "stop a violating notebook", object: raw("evil:1", true), wantAllowed: true},
+		{
+			name:       "change the image of a violating notebook",
+			object:     raw("evil:2", false),
+			wantReason: `image "evil:2" is not allowed`,
+		},
+	}
+	for _, test := range tests {
+		resp := h.Handle(context.TODO(), types.Request{AdmissionRequest: &admissionv1beta1.AdmissionRequest{
+			Operation: admissionv1beta1.Update,
+			Object:    test.object,
+			OldObject: raw("evil:1", false),
+		}})
+		if resp.Response.Allowed != test.wantAllowed {
+			t.Errorf("%s: allowed = %v (%v); want %v", test.name, resp.Response.Allowed, resp.Response.Result, test.wantAllowed)
+		}
+		if !test.wantAllowed && !strings.Contains(string(resp.Response.Result.Reason), test.wantReason) {
+			t.Errorf("%s: reason = %q; want it to contain %q", test.name, resp.Response.Result.Reason, test.wantReason)
+		}
+	}
+}
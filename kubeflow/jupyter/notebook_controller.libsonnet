@@ -17,6 +17,23 @@
         subresources: {
           status: {},
         },
+        additionalPrinterColumns: [
+          {
+            JSONPath: ".status.readyReplicas",
+            name: "Ready",
+            type: "integer",
+          },
+          {
+            JSONPath: ".status.conditions[?(@.type=='Ready')].reason",
+            name: "Status",
+            type: "string",
+          },
+          {
+            JSONPath: ".metadata.creationTimestamp",
+            name: "Age",
+            type: "date",
+          },
+        ],
         names: {
           plural: "notebooks",
           singular: "notebook",
@@ -26,6 +43,138 @@
     },
     notebooksCRD:: notebooksCRD,
 
+    local notebookTemplatesCRD = {
+      apiVersion: "apiextensions.k8s.io/v1beta1",
+      kind: "CustomResourceDefinition",
+      metadata: {
+        name: "notebooktemplates.kubeflow.org",
+      },
+      spec: {
+        group: "kubeflow.org",
+        version: "v1alpha1",
+        scope: "Namespaced",
+        additionalPrinterColumns: [
+          {
+            JSONPath: ".spec.description",
+            name: "Description",
+            type: "string",
+          },
+          {
+            JSONPath: ".metadata.creationTimestamp",
+            name: "Age",
+            type: "date",
+          },
+        ],
+        names: {
+          plural: "notebooktemplates",
+          singular: "notebooktemplate",
+          kind: "NotebookTemplate",
+        },
+      },
+    },
+    notebookTemplatesCRD:: notebookTemplatesCRD,
+
+    local clusterNotebookTemplatesCRD = {
+      apiVersion: "apiextensions.k8s.io/v1beta1",
+      kind: "CustomResourceDefinition",
+      metadata: {
+        name: "clusternotebooktemplates.kubeflow.org",
+      },
+      spec: {
+        group: "kubeflow.org",
+        version: "v1alpha1",
+        scope: "Cluster",
+        additionalPrinterColumns: [
+          {
+            JSONPath: ".spec.description",
+            name: "Description",
+            type: "string",
+          },
+          {
+            JSONPath: ".metadata.creationTimestamp",
+            name: "Age",
+            type: "date",
+          },
+        ],
+        names: {
+          plural: "clusternotebooktemplates",
+          singular: "clusternotebooktemplate",
+          kind: "ClusterNotebookTemplate",
+        },
+      },
+    },
+    clusterNotebookTemplatesCRD:: clusterNotebookTemplatesCRD,
+
+    local notebookPoliciesCRD = {
+      apiVersion: "apiextensions.k8s.io/v1beta1",
+      kind: "CustomResourceDefinition",
+      metadata: {
+        name: "notebookpolicies.kubeflow.org",
+      },
+      spec: {
+        group: "kubeflow.org",
+        version: "v1alpha1",
+        scope: "Namespaced",
+        additionalPrinterColumns: [
+          {
+            JSONPath: ".spec.maxNotebooks",
+            name: "Max-Notebooks",
+            type: "integer",
+          },
+          {
+            JSONPath: ".metadata.creationTimestamp",
+            name: "Age",
+            type: "date",
+          },
+        ],
+        names: {
+          plural: "notebookpolicies",
+          singular: "notebookpolicy",
+          kind: "NotebookPolicy",
+        },
+      },
+    },
+    notebookPoliciesCRD:: notebookPoliciesCRD,
+
+    local notebookSnapshotsCRD = {
+      apiVersion: "apiextensions.k8s.io/v1beta1",
+      kind: "CustomResourceDefinition",
+      metadata: {
+        name: "notebooksnapshots.kubeflow.org",
+      },
+      spec: {
+        group: "kubeflow.org",
+        version: "v1alpha1",
+        scope: "Namespaced",
+        subresources: {
+          status: {},
+        },
+        additionalPrinterColumns: [
+          {
+            JSONPath: ".spec.notebookName",
+            name: "Notebook",
+            type: "string",
+          },
+          {
+            JSONPath: ".status.readyToUse",
+            name: "Ready",
+            type: "boolean",
+          },
+          {
+            JSONPath: ".metadata.creationTimestamp",
+            name: "Age",
+            type: "date",
+          },
+        ],
+        names: {
+          plural: "notebooksnapshots",
+          singular: "notebooksnapshot",
+          kind: "NotebookSnapshot",
+        },
+      },
+    },
+    notebookSnapshotsCRD:: notebookSnapshotsCRD,
+
     local controllerService = {
       apiVersion: "v1",
       kind: "Service",
@@ -63,6 +212,7 @@
           metadata: {
             labels: {
               app: "notebooks-controller",
+              "control-plane": "controller-manager",
             },
           },
           spec: {
@@ -75,6 +225,63 @@
                 command: [
                   "/manager",
                 ],
+                env: [
+                  {
+                    name: "POD_NAMESPACE",
+                    valueFrom: {
+                      fieldRef: {
+                        fieldPath: "metadata.namespace",
+                      },
+                    },
+                  },
+                  {
+                    name: "SECRET_NAME",
+                    value: "notebook-webhook-server-secret",
+                  },
+                ],
+                ports: [
+                  {
+                    containerPort: 9876,
+                    name: "webhook-server",
+                    protocol: "TCP",
+                  },
+                  {
+                    containerPort: 8081,
+                    name: "health",
+                    protocol: "TCP",
+                  },
+                ],
+                livenessProbe: {
+                  httpGet: {
+                    path: "/healthz",
+                    port: "health",
+                  },
+                  initialDelaySeconds: 15,
+                  periodSeconds: 20,
+                },
+                readinessProbe: {
+                  httpGet: {
+                    path: "/readyz",
+                    port: "health",
+                  },
+                  periodSeconds: 10,
+                },
+                volumeMounts: [
+                  {
+                    mountPath: "/tmp/cert",
+                    name: "cert",
+                    readOnly: true,
+                  },
+                ],
+              },
+            ],
+            volumes: [
+              {
+                name: "cert",
+                secret: {
+                  defaultMode: 420,
+                  secretName: "notebook-webhook-server-secret",
+                },
               },
             ],
           },
@@ -83,6 +290,18 @@
     },
     controllerDeployment:: controllerDeployment,
 
+    // The certificate of the admission webhook server is written to this
+    // secret by the controller.
+    local webhookSecret = {
+      apiVersion: "v1",
+      kind: "Secret",
+      metadata: {
+        name: "notebook-webhook-server-secret",
+        namespace: params.namespace,
+      },
+    },
+    webhookSecret:: webhookSecret,
+
     local serviceAccount = {
       apiVersion: "v1",
       kind: "ServiceAccount",
@@ -110,6 +329,7 @@
           resources: [
             "statefulsets",
             "deployments",
+            "daemonsets",
           ],
           verbs: [
             "*",
@@ -121,17 +341,161 @@
           ],
           resources: [
             "services",
+            "secrets",
+            "persistentvolumeclaims",
+            "events",
+            "serviceaccounts",
           ],
           verbs: [
             "*",
           ],
         },
+        {
+          apiGroups: [
+            "rbac.authorization.k8s.io",
+          ],
+          resources: [
+            "roles",
+            "rolebindings",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "rbac.authorization.k8s.io",
+          ],
+          resources: [
+            "clusterroles",
+          ],
+          verbs: [
+            "get",
+            "list",
+            "watch",
+          ],
+        },
+        {
+          apiGroups: [
+            "storage.k8s.io",
+          ],
+          resources: [
+            "storageclasses",
+          ],
+          verbs: [
+            "get",
+            "list",
+            "watch",
+          ],
+        },
+        {
+          apiGroups: [
+            "networking.istio.io",
+          ],
+          resources: [
+            "virtualservices",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "extensions",
+          ],
+          resources: [
+            "ingresses",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "admissionregistration.k8s.io",
+          ],
+          resources: [
+            "mutatingwebhookconfigurations",
+            "validatingwebhookconfigurations",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "",
+          ],
+          resources: [
+            "pods",
+            "configmaps",
+            "namespaces",
+          ],
+          verbs: [
+            "get",
+            "list",
+            "watch",
+          ],
+        },
+        {
+          apiGroups: [
+            "",
+          ],
+          resources: [
+            "configmaps",
+          ],
+          verbs: [
+            "get",
+            "create",
+            "update",
+            "patch",
+          ],
+        },
         {
           apiGroups: [
             "kubeflow.org",
           ],
           resources: [
             "notebooks",
+            "notebooks/status",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "kubeflow.org",
+          ],
+          resources: [
+            "notebooktemplates",
+            "clusternotebooktemplates",
+            "notebookpolicies",
+          ],
+          verbs: [
+            "get",
+            "list",
+            "watch",
+          ],
+        },
+        {
+          apiGroups: [
+            "kubeflow.org",
+          ],
+          resources: [
+            "notebooksnapshots",
+            "notebooksnapshots/status",
+          ],
+          verbs: [
+            "*",
+          ],
+        },
+        {
+          apiGroups: [
+            "snapshot.storage.k8s.io",
+          ],
+          resources: [
+            "volumesnapshots",
           ],
           verbs: [
             "*",
@@ -165,7 +529,12 @@
     parts:: self,
     all:: [
       self.notebooksCRD,
+      self.notebookTemplatesCRD,
+      self.clusterNotebookTemplatesCRD,
+      self.notebookPoliciesCRD,
+      self.notebookSnapshotsCRD,
       self.controllerService,
+      self.webhookSecret,
       self.serviceAccount,
       self.controllerDeployment,
       self.role,